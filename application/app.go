@@ -3,6 +3,7 @@ package application
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // App represents a single application
@@ -25,5 +26,11 @@ type Server struct {
 
 func (s Server) String() string {
 	p, _ := json.Marshal(s.Ports)
-	return fmt.Sprintf("%s%s", s.Host, p)
+
+	h := s.Host
+	if strings.Contains(h, ":") {
+		h = "[" + h + "]"
+	}
+
+	return fmt.Sprintf("%s%s", h, p)
 }
@@ -0,0 +1,200 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bobrik/zoidberg/consul"
+)
+
+var (
+	consulAddressFlag    *string
+	consulDatacenterFlag *string
+	consulTokenFlag      *string
+	consulTagFlag        *string
+)
+
+func init() {
+	RegisterFinderMaker("consul", FinderMaker{
+		Flags: func() {
+			consulAddressFlag = flag.String(
+				"application-finder-consul-address",
+				os.Getenv("APPLICATION_FINDER_CONSUL_ADDRESS"),
+				"consul address (http://host:port) for consul application finder",
+			)
+
+			consulDatacenterFlag = flag.String(
+				"application-finder-consul-datacenter",
+				os.Getenv("APPLICATION_FINDER_CONSUL_DATACENTER"),
+				"consul datacenter for consul application finder, defaults to agent's datacenter",
+			)
+
+			consulTokenFlag = flag.String(
+				"application-finder-consul-token",
+				os.Getenv("APPLICATION_FINDER_CONSUL_TOKEN"),
+				"consul acl token for consul application finder",
+			)
+
+			consulTagFlag = flag.String(
+				"application-finder-consul-tag",
+				os.Getenv("APPLICATION_FINDER_CONSUL_TAG"),
+				"tag of consul services to watch in addition to services with zoidberg_* tags, required for services described only with meta",
+			)
+		},
+		Maker: func(balancer string) (Finder, error) {
+			return NewConsulFinder(*consulAddressFlag, *consulDatacenterFlag, *consulTokenFlag, *consulTagFlag, balancer)
+		},
+	})
+}
+
+// ConsulFinder represents a finder that finds apps in Consul, services
+// are described with zoidberg_app_name, zoidberg_app_version and
+// zoidberg_balanced_by service meta or tags in key=value format
+type ConsulFinder struct {
+	watcher    *consul.Watcher
+	address    string
+	datacenter string
+	tag        string
+	balancer   string
+}
+
+// NewConsulFinder creates a new Consul finder that watches services
+// that have the tag or zoidberg_* tags with blocking queries
+func NewConsulFinder(address, datacenter, token, tag, balancer string) (*ConsulFinder, error) {
+	if address == "" {
+		return nil, errors.New("empty consul address for consul application finder")
+	}
+
+	filter := func(tags []string) bool {
+		for _, t := range tags {
+			if (tag != "" && t == tag) || strings.HasPrefix(t, "zoidberg_") {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return &ConsulFinder{
+		watcher:    consul.NewWatcher(consul.NewClient(address, datacenter, token), filter),
+		address:    address,
+		datacenter: datacenter,
+		tag:        tag,
+		balancer:   balancer,
+	}, nil
+}
+
+// Apps returns our applications registered in Consul
+func (c *ConsulFinder) Apps(ctx context.Context) (Apps, error) {
+	groups, err := c.GroupApps(ctx, []string{c.balancer})
+	if err != nil {
+		return nil, err
+	}
+
+	return groups[c.balancer], nil
+}
+
+// Source returns identifier of Consul services the finder watches
+func (c *ConsulFinder) Source() string {
+	return fmt.Sprintf("consul:%s:%s:%s", c.address, c.datacenter, c.tag)
+}
+
+// GroupApps returns applications registered in Consul
+// for each of the specified balancers
+func (c *ConsulFinder) GroupApps(ctx context.Context, balancers []string) (map[string]Apps, error) {
+	services, err := c.watcher.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]Apps, len(balancers))
+	for _, b := range balancers {
+		groups[b] = Apps{}
+	}
+
+	for service, entries := range services {
+		for _, e := range entries {
+			labels := consulLabels(e.Service)
+
+			apps, ok := groups[labels["balanced_by"]]
+			if !ok {
+				continue
+			}
+
+			name := labels["app_name"]
+			if name == "" {
+				name = service
+			}
+
+			version := labels["app_version"]
+			if version == "" {
+				version = "1"
+			}
+
+			host := e.Service.Address
+			if host == "" {
+				host = e.Node.Address
+			}
+
+			app := apps[name]
+			if app.Name == "" {
+				app.Name = name
+				app.Servers = []Server{}
+				app.Meta = labels
+			}
+
+			app.Servers = append(app.Servers, Server{
+				Host:    host,
+				Port:    e.Service.Port,
+				Ports:   []int{e.Service.Port},
+				Version: version,
+			})
+
+			apps[name] = app
+		}
+	}
+
+	for _, apps := range groups {
+		for name, app := range apps {
+			sort.Slice(app.Servers, func(i, j int) bool {
+				return app.Servers[i].String() < app.Servers[j].String()
+			})
+
+			apps[name] = app
+		}
+	}
+
+	return groups, nil
+}
+
+// consulLabels returns zoidberg labels of the service without prefix,
+// service meta takes precedence over tags in key=value format
+func consulLabels(s consul.Service) map[string]string {
+	labels := map[string]string{}
+
+	for _, t := range s.Tags {
+		if !strings.HasPrefix(t, "zoidberg_") {
+			continue
+		}
+
+		kv := strings.SplitN(strings.TrimPrefix(t, "zoidberg_"), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		labels[kv[0]] = kv[1]
+	}
+
+	for k, v := range s.Meta {
+		if strings.HasPrefix(k, "zoidberg_") {
+			labels[strings.TrimPrefix(k, "zoidberg_")] = v
+		}
+	}
+
+	return labels
+}
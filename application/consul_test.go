@@ -0,0 +1,205 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bobrik/zoidberg/consul"
+)
+
+// fakeConsul serves catalog and health endpoints with blocking queries
+type fakeConsul struct {
+	index    uint64
+	changed  chan struct{}
+	services map[string][]string
+	entries  map[string][]consul.ServiceEntry
+	requests map[string]int
+	done     chan struct{}
+	mutex    sync.Mutex
+}
+
+func newFakeConsul() *fakeConsul {
+	return &fakeConsul{
+		index:    1,
+		changed:  make(chan struct{}),
+		services: map[string][]string{},
+		entries:  map[string][]consul.ServiceEntry{},
+		requests: map[string]int{},
+		done:     make(chan struct{}),
+	}
+}
+
+func (f *fakeConsul) update(fn func()) {
+	f.mutex.Lock()
+	fn()
+	f.index++
+	close(f.changed)
+	f.changed = make(chan struct{})
+	f.mutex.Unlock()
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	index, _ := strconv.ParseUint(req.URL.Query().Get("index"), 10, 64)
+	wait, _ := time.ParseDuration(req.URL.Query().Get("wait"))
+
+	f.mutex.Lock()
+	f.requests[req.URL.Path]++
+	current, changed := f.index, f.changed
+	f.mutex.Unlock()
+
+	if index > 0 && index == current {
+		select {
+		case <-changed:
+		case <-time.After(wait):
+		case <-f.done:
+			return
+		}
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var result interface{}
+
+	switch {
+	case req.URL.Path == "/v1/catalog/services":
+		result = f.services
+	case strings.HasPrefix(req.URL.Path, "/v1/health/service/"):
+		if req.URL.Query().Get("passing") != "true" {
+			http.Error(w, "expected passing instances to be requested", http.StatusBadRequest)
+			return
+		}
+
+		result = f.entries[strings.TrimPrefix(req.URL.Path, "/v1/health/service/")]
+	default:
+		http.NotFound(w, req)
+		return
+	}
+
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+	json.NewEncoder(w).Encode(result)
+}
+
+func TestConsulFinder(t *testing.T) {
+	f := newFakeConsul()
+
+	f.services = map[string][]string{
+		"web":       {"zoidberg_balanced_by=lb", "zoidberg_app_version=2"},
+		"api":       {"zoidberg"},
+		"elsewhere": {"zoidberg_balanced_by=other"},
+		"unrelated": {"db"},
+	}
+
+	f.entries = map[string][]consul.ServiceEntry{
+		"web": {
+			{
+				Node:    consul.Node{Node: "n1", Address: "10.0.0.1"},
+				Service: consul.Service{Service: "web", Tags: f.services["web"], Port: 8080},
+			},
+			{
+				Node:    consul.Node{Node: "n2", Address: "10.0.0.2"},
+				Service: consul.Service{Service: "web", Tags: f.services["web"], Address: "172.16.0.2", Port: 8081},
+			},
+		},
+		"api": {
+			{
+				Node: consul.Node{Node: "n1", Address: "10.0.0.1"},
+				Service: consul.Service{
+					Service: "api",
+					Tags:    f.services["api"],
+					Port:    9090,
+					Meta:    map[string]string{"zoidberg_app_name": "public-api", "zoidberg_balanced_by": "lb"},
+				},
+			},
+		},
+		"elsewhere": {
+			{
+				Node:    consul.Node{Node: "n3", Address: "10.0.0.3"},
+				Service: consul.Service{Service: "elsewhere", Tags: f.services["elsewhere"], Port: 1},
+			},
+		},
+	}
+
+	s := httptest.NewServer(f)
+	defer s.Close()
+	defer close(f.done)
+
+	finder, err := NewConsulFinder(s.URL, "", "", "zoidberg", "lb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	apps, err := finder.Apps(ctx)
+	if err != nil {
+		t.Fatalf("error getting apps: %s", err)
+	}
+
+	expected := Apps{
+		"web": App{
+			Name: "web",
+			Servers: []Server{
+				{Host: "10.0.0.1", Port: 8080, Ports: []int{8080}, Version: "2"},
+				{Host: "172.16.0.2", Port: 8081, Ports: []int{8081}, Version: "2"},
+			},
+			Meta: map[string]string{"balanced_by": "lb", "app_version": "2"},
+		},
+		"public-api": App{
+			Name: "public-api",
+			Servers: []Server{
+				{Host: "10.0.0.1", Port: 9090, Ports: []int{9090}, Version: "1"},
+			},
+			Meta: map[string]string{"app_name": "public-api", "balanced_by": "lb"},
+		},
+	}
+
+	if !reflect.DeepEqual(apps, expected) {
+		t.Errorf("expected: %#v, got: %#v", expected, apps)
+	}
+
+	// instance fails health checks and is not passing anymore
+	f.update(func() {
+		f.entries["web"] = f.entries["web"][1:]
+	})
+
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		apps, err = finder.Apps(ctx)
+		if err != nil {
+			t.Fatalf("error getting apps: %s", err)
+		}
+
+		if len(apps["web"].Servers) == 1 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected failing instance to be removed, got: %v", apps["web"].Servers)
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.requests["/v1/health/service/unrelated"] != 0 {
+		t.Errorf("expected unrelated service not to be watched")
+	}
+
+	// initial request and the one that returned after the update,
+	// the next one is blocked waiting for changes
+	if f.requests["/v1/health/service/web"] > 3 {
+		t.Errorf("expected blocking queries, got %d requests", f.requests["/v1/health/service/web"])
+	}
+}
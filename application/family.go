@@ -0,0 +1,73 @@
+package application
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+)
+
+var addressFamilyFlag *string
+
+// AddressFamily defines which addresses are preferred
+// when a task has addresses of more than one family
+type AddressFamily string
+
+const (
+	// AddressFamilyAny picks the first reported address
+	AddressFamilyAny AddressFamily = ""
+	// AddressFamilyIPv4 prefers IPv4 addresses
+	AddressFamilyIPv4 AddressFamily = "ipv4"
+	// AddressFamilyIPv6 prefers IPv6 addresses
+	AddressFamilyIPv6 AddressFamily = "ipv6"
+)
+
+// ParseAddressFamily returns address family from its string representation
+func ParseAddressFamily(s string) (AddressFamily, error) {
+	switch f := AddressFamily(s); f {
+	case AddressFamilyAny, AddressFamilyIPv4, AddressFamilyIPv6:
+		return f, nil
+	default:
+		return AddressFamilyAny, fmt.Errorf("unknown address family %q, expected ipv4 or ipv6", s)
+	}
+}
+
+// registerAddressFamilyFlag registers flag shared by all finders
+func registerAddressFamilyFlag() {
+	addressFamilyFlag = flag.String(
+		"application-address-family",
+		os.Getenv("APPLICATION_ADDRESS_FAMILY"),
+		"preferred address family (ipv4 or ipv6) for tasks with several addresses",
+	)
+}
+
+// addressFamilyFromFlag returns address family specified in cli flag
+func addressFamilyFromFlag() (AddressFamily, error) {
+	if addressFamilyFlag == nil {
+		return AddressFamilyAny, nil
+	}
+
+	return ParseAddressFamily(*addressFamilyFlag)
+}
+
+// pick returns the first address of the preferred family,
+// falling back to the first address if there is no such address
+func (f AddressFamily) pick(addresses []string) string {
+	if len(addresses) == 0 {
+		return ""
+	}
+
+	for _, a := range addresses {
+		ip := net.ParseIP(a)
+		if ip == nil {
+			continue
+		}
+
+		v4 := ip.To4() != nil
+		if (f == AddressFamilyIPv4 && v4) || (f == AddressFamilyIPv6 && !v4) {
+			return a
+		}
+	}
+
+	return addresses[0]
+}
@@ -0,0 +1,44 @@
+package application
+
+import "testing"
+
+func TestAddressFamilyPick(t *testing.T) {
+	table := []struct {
+		family    AddressFamily
+		addresses []string
+		address   string
+	}{
+		{
+			family:    AddressFamilyAny,
+			addresses: nil,
+			address:   "",
+		},
+		{
+			family:    AddressFamilyAny,
+			addresses: []string{"2001:db8::1", "10.0.0.1"},
+			address:   "2001:db8::1",
+		},
+		{
+			family:    AddressFamilyIPv4,
+			addresses: []string{"2001:db8::1", "10.0.0.1"},
+			address:   "10.0.0.1",
+		},
+		{
+			family:    AddressFamilyIPv6,
+			addresses: []string{"10.0.0.1", "2001:db8::1"},
+			address:   "2001:db8::1",
+		},
+		{
+			family:    AddressFamilyIPv6,
+			addresses: []string{"10.0.0.1", "10.0.0.2"},
+			address:   "10.0.0.1",
+		},
+	}
+
+	for _, row := range table {
+		a := row.family.pick(row.addresses)
+		if a != row.address {
+			t.Errorf("expected %q for %q from %v, got: %q", row.address, row.family, row.addresses, a)
+		}
+	}
+}
@@ -0,0 +1,474 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bobrik/zoidberg/watch"
+	"gopkg.in/yaml.v3"
+)
+
+var filePathFlag *string
+
+func init() {
+	RegisterFinderMaker("file", FinderMaker{
+		Flags: func() {
+			filePathFlag = flag.String(
+				"application-finder-file-path",
+				os.Getenv("APPLICATION_FINDER_FILE_PATH"),
+				"json or yaml file with apps for file application finder, reloaded on change",
+			)
+		},
+		Maker: func(balancer string) (Finder, error) {
+			return NewFileFinder(*filePathFlag, balancer)
+		},
+	})
+}
+
+// FileFinder represents a finder that reads apps from a json or yaml file,
+// the file is read again when it changes
+type FileFinder struct {
+	file     *watch.File
+	balancer string
+}
+
+// fileApp is an app defined in the file
+type fileApp struct {
+	App
+	BalancedBy string
+}
+
+// fileServer is a server of an app defined in the file
+type fileServer struct {
+	Host    string `json:"host"`
+	Port    int    `json:"port"`
+	Ports   []int  `json:"ports"`
+	Version string `json:"version"`
+}
+
+// NewFileFinder creates a new file finder for the file, files
+// with .yaml and .yml extensions are parsed as yaml
+func NewFileFinder(path, balancer string) (*FileFinder, error) {
+	if path == "" {
+		return nil, errors.New("empty file path for file application finder")
+	}
+
+	parse := parseAppsFile
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		parse = parseAppsYAMLFile
+	}
+
+	file, err := watch.NewFile(path, func(b []byte) (interface{}, error) {
+		return parse(b)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileFinder{
+		file:     file,
+		balancer: balancer,
+	}, nil
+}
+
+// Apps returns our applications defined in the file
+func (f *FileFinder) Apps(ctx context.Context) (Apps, error) {
+	groups, err := f.GroupApps(ctx, []string{f.balancer})
+	if err != nil {
+		return nil, err
+	}
+
+	return groups[f.balancer], nil
+}
+
+// Source returns path of the file the finder reads
+func (f *FileFinder) Source() string {
+	return "file:" + f.file.Path()
+}
+
+// Close stops watching the file
+func (f *FileFinder) Close() error {
+	return f.file.Close()
+}
+
+// GroupApps returns applications defined in the file for each of the
+// specified balancers, apps without balanced_by belong to all of them
+func (f *FileFinder) GroupApps(ctx context.Context, balancers []string) (map[string]Apps, error) {
+	v, err := f.file.Value()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]Apps, len(balancers))
+	for _, b := range balancers {
+		groups[b] = Apps{}
+	}
+
+	for _, a := range v.([]fileApp) {
+		for b, group := range groups {
+			if a.BalancedBy == "" || a.BalancedBy == b {
+				group[a.Name] = a.App
+			}
+		}
+	}
+
+	return groups, nil
+}
+
+// parseAppsFile parses apps keyed by name in the same shape as apps
+// in the discovery api, errors are prefixed with line and column
+// of the invalid value:
+//
+//	{
+//	  "myapp": {
+//	    "name": "myapp",
+//	    "balanced_by": "lb",
+//	    "servers": [
+//	      {"host": "10.0.0.1", "port": 8080, "version": "1"}
+//	    ],
+//	    "meta": {}
+//	  }
+//	}
+func parseAppsFile(b []byte) ([]fileApp, error) {
+	var v interface{}
+	err := json.Unmarshal(b, &v)
+	if err != nil {
+		return nil, jsonError(b, 0, err)
+	}
+
+	apps := []fileApp{}
+
+	err = jsonObject(b, b, 0, func(name string, value []byte, offset int64) error {
+		if name == "" {
+			return positionError(b, offset, errors.New("empty app name"))
+		}
+
+		a := fileApp{
+			App: App{
+				Name:    name,
+				Servers: []Server{},
+				Meta:    map[string]string{},
+			},
+		}
+
+		err := jsonObject(b, value, offset, func(key string, value []byte, offset int64) error {
+			var err error
+
+			switch key {
+			case "name":
+				err = json.Unmarshal(value, &a.Name)
+				if err == nil && a.Name != name {
+					err = fmt.Errorf("name %q does not match the key", a.Name)
+				}
+			case "balanced_by":
+				err = json.Unmarshal(value, &a.BalancedBy)
+			case "meta":
+				err = json.Unmarshal(value, &a.Meta)
+				if a.Meta == nil {
+					a.Meta = map[string]string{}
+				}
+			case "servers":
+				if string(value) == "null" {
+					return nil
+				}
+
+				return jsonArray(b, value, offset, func(value []byte, offset int64) error {
+					s, err := parseFileServer(value)
+					if err != nil {
+						return positionError(b, offset, fmt.Errorf("app %q: %s", name, err))
+					}
+
+					a.Servers = append(a.Servers, s)
+
+					return nil
+				})
+			default:
+				err = fmt.Errorf("unknown field %q", key)
+			}
+
+			if err != nil {
+				return positionError(b, offset, fmt.Errorf("app %q: %s", name, err))
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		apps = append(apps, a)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].Name < apps[j].Name
+	})
+
+	return apps, nil
+}
+
+// parseAppsYAMLFile parses apps in the same shape as parseAppsFile
+// from yaml, errors are prefixed with line and column of the invalid value
+func parseAppsYAMLFile(b []byte) ([]fileApp, error) {
+	doc := yaml.Node{}
+	err := yaml.Unmarshal(b, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	apps := []fileApp{}
+
+	if len(doc.Content) == 0 {
+		return apps, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, yamlError(root, errors.New("expected mapping"))
+	}
+
+	for i := 0; i < len(root.Content); i += 2 {
+		name, value := root.Content[i].Value, root.Content[i+1]
+
+		if name == "" {
+			return nil, yamlError(root.Content[i], errors.New("empty app name"))
+		}
+
+		a := fileApp{
+			App: App{
+				Name:    name,
+				Servers: []Server{},
+				Meta:    map[string]string{},
+			},
+		}
+
+		if value.Tag == "!!null" {
+			apps = append(apps, a)
+			continue
+		}
+
+		if value.Kind != yaml.MappingNode {
+			return nil, yamlError(value, errors.New("expected mapping"))
+		}
+
+		for j := 0; j < len(value.Content); j += 2 {
+			key, value := value.Content[j].Value, value.Content[j+1]
+
+			switch key {
+			case "name":
+				err = value.Decode(&a.Name)
+				if err == nil && a.Name != name {
+					err = fmt.Errorf("name %q does not match the key", a.Name)
+				}
+			case "balanced_by":
+				err = value.Decode(&a.BalancedBy)
+			case "meta":
+				err = value.Decode(&a.Meta)
+				if a.Meta == nil {
+					a.Meta = map[string]string{}
+				}
+			case "servers":
+				if value.Tag == "!!null" {
+					continue
+				}
+
+				if value.Kind != yaml.SequenceNode {
+					return nil, yamlError(value, fmt.Errorf("app %q: expected sequence", name))
+				}
+
+				for _, node := range value.Content {
+					s, err := parseYAMLFileServer(node)
+					if err != nil {
+						return nil, yamlError(node, fmt.Errorf("app %q: %s", name, err))
+					}
+
+					a.Servers = append(a.Servers, s)
+				}
+			default:
+				err = fmt.Errorf("unknown field %q", key)
+			}
+
+			if err != nil {
+				return nil, yamlError(value, fmt.Errorf("app %q: %s", name, err))
+			}
+		}
+
+		apps = append(apps, a)
+	}
+
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].Name < apps[j].Name
+	})
+
+	return apps, nil
+}
+
+// parseYAMLFileServer parses and validates a server of an app
+// from yaml with the same rules as for json
+func parseYAMLFileServer(node *yaml.Node) (Server, error) {
+	var v interface{}
+	err := node.Decode(&v)
+	if err != nil {
+		return Server{}, err
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return Server{}, err
+	}
+
+	return parseFileServer(b)
+}
+
+// parseFileServer parses and validates a server of an app
+func parseFileServer(value []byte) (Server, error) {
+	fs := fileServer{}
+
+	d := json.NewDecoder(bytes.NewReader(value))
+	d.DisallowUnknownFields()
+
+	err := d.Decode(&fs)
+	if err != nil {
+		return Server{}, err
+	}
+
+	if fs.Host == "" {
+		return Server{}, errors.New("empty server host")
+	}
+
+	if len(fs.Ports) == 0 {
+		fs.Ports = []int{fs.Port}
+	}
+
+	for _, p := range append([]int{fs.Port}, fs.Ports...) {
+		if p < 1 || p > 65535 {
+			return Server{}, fmt.Errorf("invalid server port %d", p)
+		}
+	}
+
+	if fs.Version == "" {
+		fs.Version = "1"
+	}
+
+	return Server{
+		Host:    fs.Host,
+		Port:    fs.Port,
+		Ports:   fs.Ports,
+		Version: fs.Version,
+	}, nil
+}
+
+// jsonObject calls f for every key of json object with its value
+// and offset of the value in the whole input, value must be
+// a slice of input starting at the specified offset
+func jsonObject(input, value []byte, offset int64, f func(key string, value []byte, offset int64) error) error {
+	d := json.NewDecoder(bytes.NewReader(value))
+
+	t, err := d.Token()
+	if err != nil {
+		return jsonError(input, offset, err)
+	}
+
+	if t != json.Delim('{') {
+		return positionError(input, offset, errors.New("expected object"))
+	}
+
+	for d.More() {
+		t, err = d.Token()
+		if err != nil {
+			return jsonError(input, offset, err)
+		}
+
+		key := t.(string)
+
+		raw := json.RawMessage{}
+		err = d.Decode(&raw)
+		if err != nil {
+			return jsonError(input, offset, err)
+		}
+
+		start := offset + d.InputOffset() - int64(len(raw))
+
+		err = f(key, raw, start)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// jsonArray calls f for every element of json array with its value
+// and offset of the value in the whole input
+func jsonArray(input, value []byte, offset int64, f func(value []byte, offset int64) error) error {
+	d := json.NewDecoder(bytes.NewReader(value))
+
+	t, err := d.Token()
+	if err != nil {
+		return jsonError(input, offset, err)
+	}
+
+	if t != json.Delim('[') {
+		return positionError(input, offset, errors.New("expected array"))
+	}
+
+	for d.More() {
+		raw := json.RawMessage{}
+		err = d.Decode(&raw)
+		if err != nil {
+			return jsonError(input, offset, err)
+		}
+
+		err = f(raw, offset+d.InputOffset()-int64(len(raw)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// jsonError adds position to json errors that report offsets
+func jsonError(input []byte, offset int64, err error) error {
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		// syntax error offset is after the invalid character
+		return positionError(input, offset+e.Offset-1, err)
+	case *json.UnmarshalTypeError:
+		return positionError(input, offset+e.Offset, err)
+	default:
+		return positionError(input, offset, err)
+	}
+}
+
+// yamlError prefixes error with line and column of the yaml node
+func yamlError(node *yaml.Node, err error) error {
+	return fmt.Errorf("%d:%d: %s", node.Line, node.Column, err)
+}
+
+// positionError prefixes error with line and column of the offset
+func positionError(input []byte, offset int64, err error) error {
+	if offset > int64(len(input)) {
+		offset = int64(len(input))
+	}
+
+	line := bytes.Count(input[:offset], []byte("\n")) + 1
+	column := offset - int64(bytes.LastIndexByte(input[:offset], '\n'))
+
+	return fmt.Errorf("%d:%d: %s", line, column, err)
+}
@@ -0,0 +1,269 @@
+package application
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseAppsFile(t *testing.T) {
+	table := []struct {
+		input string
+		apps  []fileApp
+		err   string
+	}{
+		{
+			input: `{
+  "web": {
+    "balanced_by": "lb",
+    "servers": [
+      {"host": "10.0.0.1", "port": 8080, "version": "2"},
+      {"host": "10.0.0.2", "port": 8080, "ports": [8080, 9090]}
+    ],
+    "meta": {"owner": "team"}
+  },
+  "api": {}
+}`,
+			apps: []fileApp{
+				{
+					App: App{
+						Name:    "api",
+						Servers: []Server{},
+						Meta:    map[string]string{},
+					},
+				},
+				{
+					App: App{
+						Name: "web",
+						Servers: []Server{
+							{Host: "10.0.0.1", Port: 8080, Ports: []int{8080}, Version: "2"},
+							{Host: "10.0.0.2", Port: 8080, Ports: []int{8080, 9090}, Version: "1"},
+						},
+						Meta: map[string]string{"owner": "team"},
+					},
+					BalancedBy: "lb",
+				},
+			},
+		},
+		{
+			input: "{\n  \"web\": {\n    \"servers\": [\n      {\"host\": \"a\", \"port\": 1}\n      {\"host\": \"b\"}\n    ]\n  }\n}",
+			err:   "5:7: invalid character '{' after array element",
+		},
+		{
+			input: "{\n  \"web\": {\n    \"servers\": [\n      {\"host\": \"a\", \"port\": 1},\n      {\"host\": \"b\"}\n    ]\n  }\n}",
+			err:   "5:7: app \"web\": invalid server port 0",
+		},
+		{
+			input: "{\n  \"web\": {\n    \"servers\": [\n      {\"host\": \"\", \"port\": 1}\n    ]\n  }\n}",
+			err:   "4:7: app \"web\": empty server host",
+		},
+		{
+			input: "{\n  \"web\": {\n    \"servers\": [\n      {\"host\": \"a\", \"port\": \"1\"}\n    ]\n  }\n}",
+			err:   "4:7: app \"web\": json: cannot unmarshal string into Go struct field fileServer.port of type int",
+		},
+		{
+			input: "{\n  \"web\": {\n    \"balancer\": \"lb\"\n  }\n}",
+			err:   "3:17: app \"web\": unknown field \"balancer\"",
+		},
+		{
+			input: `{"web": {"name": "web", "servers": null, "meta": null}}`,
+			apps: []fileApp{
+				{
+					App: App{
+						Name:    "web",
+						Servers: []Server{},
+						Meta:    map[string]string{},
+					},
+				},
+			},
+		},
+		{
+			input: "{\n  \"web\": {\n    \"name\": \"api\"\n  }\n}",
+			err:   "3:13: app \"web\": name \"api\" does not match the key",
+		},
+		{
+			input: "{\n  \"web\": []\n}",
+			err:   "2:10: expected object",
+		},
+		{
+			input: "[]",
+			err:   "1:1: expected object",
+		},
+	}
+
+	for i, row := range table {
+		apps, err := parseAppsFile([]byte(row.input))
+		if err != nil {
+			if err.Error() != row.err {
+				t.Errorf("row %d: expected error: %q, got: %q", i, row.err, err)
+			}
+
+			continue
+		}
+
+		if row.err != "" {
+			t.Errorf("row %d: expected error: %q, got nothing", i, row.err)
+			continue
+		}
+
+		if !reflect.DeepEqual(apps, row.apps) {
+			t.Errorf("row %d: expected: %#v, got: %#v", i, row.apps, apps)
+		}
+	}
+}
+
+func TestParseAppsYAMLFile(t *testing.T) {
+	table := []struct {
+		input string
+		apps  []fileApp
+		err   string
+	}{
+		{
+			input: `
+web:
+  name: web
+  balanced_by: lb
+  servers:
+    - host: 10.0.0.1
+      port: 8080
+      version: "2"
+    - {host: 10.0.0.2, port: 8080, ports: [8080, 9090]}
+  meta:
+    owner: team
+api:
+`,
+			apps: []fileApp{
+				{
+					App: App{
+						Name:    "api",
+						Servers: []Server{},
+						Meta:    map[string]string{},
+					},
+				},
+				{
+					App: App{
+						Name: "web",
+						Servers: []Server{
+							{Host: "10.0.0.1", Port: 8080, Ports: []int{8080}, Version: "2"},
+							{Host: "10.0.0.2", Port: 8080, Ports: []int{8080, 9090}, Version: "1"},
+						},
+						Meta: map[string]string{"owner": "team"},
+					},
+					BalancedBy: "lb",
+				},
+			},
+		},
+		{
+			input: "",
+			apps:  []fileApp{},
+		},
+		{
+			input: "web:\n  servers:\n    - host: a\n      port: 1\n    - host: b\n",
+			err:   "5:7: app \"web\": invalid server port 0",
+		},
+		{
+			input: "web:\n  servers:\n    - host: a\n      port: 1\n      weight: 2\n",
+			err:   "3:7: app \"web\": json: unknown field \"weight\"",
+		},
+		{
+			input: "web:\n  name: api\n",
+			err:   "2:9: app \"web\": name \"api\" does not match the key",
+		},
+		{
+			input: "web:\n  balancer: lb\n",
+			err:   "2:13: app \"web\": unknown field \"balancer\"",
+		},
+		{
+			input: "web:\n  servers: a\n",
+			err:   "2:12: app \"web\": expected sequence",
+		},
+		{
+			input: "- web\n",
+			err:   "1:1: expected mapping",
+		},
+		{
+			input: "web: [\n",
+			err:   "yaml: line 1: did not find expected node content",
+		},
+	}
+
+	for i, row := range table {
+		apps, err := parseAppsYAMLFile([]byte(row.input))
+		if err != nil {
+			if err.Error() != row.err {
+				t.Errorf("row %d: expected error: %q, got: %q", i, row.err, err)
+			}
+
+			continue
+		}
+
+		if row.err != "" {
+			t.Errorf("row %d: expected error: %q, got nothing", i, row.err)
+			continue
+		}
+
+		if !reflect.DeepEqual(apps, row.apps) {
+			t.Errorf("row %d: expected: %#v, got: %#v", i, row.apps, apps)
+		}
+	}
+}
+
+func TestFileFinderReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zoidberg-file-finder")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "apps.json")
+
+	write := func(content string, modified time.Time) {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+
+	write(`{"web": {"servers": [{"host": "a", "port": 1}]}, "other": {"balanced_by": "elsewhere"}}`, now)
+
+	f, err := NewFileFinder(path, "lb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apps, err := f.Apps(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(apps) != 1 || len(apps["web"].Servers) != 1 {
+		t.Errorf("expected web app with one server, got: %v", apps)
+	}
+
+	write(`{"web": {"servers": [{"host": "a", "port": 1}, {"host": "b", "port": 1}]}}`, now.Add(time.Second))
+
+	apps, err = f.Apps(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(apps["web"].Servers) != 2 {
+		t.Errorf("expected reloaded web app with two servers, got: %v", apps)
+	}
+
+	write(`{"web": {"servers": [}}`, now.Add(time.Second*2))
+
+	if _, err = f.Apps(context.Background()); err == nil {
+		t.Errorf("expected error for invalid file")
+	}
+}
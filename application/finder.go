@@ -1,12 +1,110 @@
 package application
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bobrik/zoidberg/logging"
 )
 
+var logger = logging.Component("application")
+
 // Finder finds apps
 type Finder interface {
-	Apps() (Apps, error)
+	Apps(ctx context.Context) (Apps, error)
+}
+
+// GroupFinder is a Finder that can find apps for several balancer groups
+// at once, so cluster state is only fetched once for all of them
+type GroupFinder interface {
+	Finder
+	// Source identifies where apps come from, finders with
+	// the same source return the same apps for the same balancers
+	Source() string
+	// GroupApps returns apps for each of the specified balancers
+	GroupApps(ctx context.Context, balancers []string) (map[string]Apps, error)
+}
+
+// SourceStatus is the status of a source of apps of a finder
+type SourceStatus struct {
+	Source       string     `json:"source"`
+	Apps         int        `json:"apps"`
+	LastSuccess  *time.Time `json:"last_success,omitempty"`
+	FailingSince *time.Time `json:"failing_since,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	Stale        bool       `json:"stale"`
+}
+
+// SourceStatusReporter is a Finder that combines several sources of apps
+// and reports their statuses, failing sources may serve stale apps
+type SourceStatusReporter interface {
+	Finder
+	SourceStatuses() []SourceStatus
+}
+
+// FindGroupApps returns apps for each balancer group with its finder,
+// fetching state only once for group finders that share the same source,
+// observe is called after every finder call with its source, finders
+// that are not group finders are identified by their group names
+func FindGroupApps(ctx context.Context, finders map[string]Finder, observe func(source string, started time.Time, err error)) (map[string]Apps, error) {
+	groups := make(map[string]Apps, len(finders))
+
+	sources := map[string]GroupFinder{}
+	balancers := map[string][]string{}
+
+	names := make([]string, 0, len(finders))
+	for b := range finders {
+		names = append(names, b)
+	}
+
+	// the finder of the first group is used for every source
+	sort.Strings(names)
+
+	for _, b := range names {
+		f := finders[b]
+
+		if gf, ok := f.(GroupFinder); ok {
+			s := gf.Source()
+			if _, ok := sources[s]; !ok {
+				sources[s] = gf
+			}
+
+			balancers[s] = append(balancers[s], b)
+			continue
+		}
+
+		started := time.Now()
+		apps, err := f.Apps(ctx)
+		if observe != nil {
+			observe("group:"+b, started, err)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		groups[b] = apps
+	}
+
+	for s, gf := range sources {
+		started := time.Now()
+		r, err := gf.GroupApps(ctx, balancers[s])
+		if observe != nil {
+			observe(s, started, err)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range balancers[s] {
+			groups[b] = r[b]
+		}
+	}
+
+	return groups, nil
 }
 
 // FinderMaker represents finder maker tuple:
@@ -36,6 +134,8 @@ func FinderByName(finder string, balancer string) (Finder, error) {
 
 // RegisterFlags registers flags of all finder makers
 func RegisterFlags() {
+	registerAddressFamilyFlag()
+
 	for _, m := range finderMakers {
 		m.Flags()
 	}
@@ -0,0 +1,84 @@
+package application
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+type testFinder struct {
+	apps Apps
+}
+
+func (f testFinder) Apps(ctx context.Context) (Apps, error) {
+	return f.apps, nil
+}
+
+type testGroupFinder struct {
+	source string
+	groups map[string]Apps
+	calls  *int
+}
+
+func (f testGroupFinder) Apps(ctx context.Context) (Apps, error) {
+	panic("Apps should not be called on group finders")
+}
+
+func (f testGroupFinder) Source() string {
+	return f.source
+}
+
+func (f testGroupFinder) GroupApps(ctx context.Context, balancers []string) (map[string]Apps, error) {
+	*f.calls++
+
+	r := map[string]Apps{}
+	for _, b := range balancers {
+		r[b] = f.groups[b]
+	}
+
+	return r, nil
+}
+
+func TestFindGroupApps(t *testing.T) {
+	calls := 0
+	others := 0
+
+	groups := map[string]Apps{
+		"a": {"foo": App{Name: "foo"}},
+		"b": {"bar": App{Name: "bar"}},
+	}
+
+	static := Apps{"baz": App{Name: "baz"}}
+
+	finders := map[string]Finder{
+		"a": testGroupFinder{source: "cluster", groups: groups, calls: &calls},
+		"b": testGroupFinder{source: "cluster", groups: groups, calls: &others},
+		"c": testFinder{apps: static},
+	}
+
+	r, err := FindGroupApps(context.Background(), finders, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// map iteration order must not change the finder used for a source
+	for i := 0; i < 20; i++ {
+		if _, err = FindGroupApps(context.Background(), finders, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[string]Apps{
+		"a": groups["a"],
+		"b": groups["b"],
+		"c": static,
+	}
+
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("expected: %v, got: %v", expected, r)
+	}
+
+	if calls != 21 || others != 0 {
+		t.Errorf("expected state to be fetched once for the same source by the first group, got %d and %d fetches", calls, others)
+	}
+}
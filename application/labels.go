@@ -1,11 +1,30 @@
 package application
 
 import (
-	"log"
+	"fmt"
 	"strconv"
 	"strings"
 )
 
+const (
+	// addressModeHost makes servers use agent host and allocated host ports
+	addressModeHost = "host"
+	// addressModeContainer makes servers use container ip and container ports
+	addressModeContainer = "container"
+)
+
+// addressMode returns address mode requested by app labels
+func addressMode(labels map[string]string) (string, error) {
+	switch labels["address_mode"] {
+	case "", addressModeHost:
+		return addressModeHost, nil
+	case addressModeContainer:
+		return addressModeContainer, nil
+	default:
+		return "", fmt.Errorf("unknown address mode %q", labels["address_mode"])
+	}
+}
+
 // extractApps returns map{ port => labels } for all found apps
 func extractApps(labels map[string]string) map[int]map[string]string {
 	r := map[int]map[string]string{}
@@ -17,12 +36,12 @@ func extractApps(labels map[string]string) map[int]map[string]string {
 
 		p := strings.SplitN(strings.TrimPrefix(k, "zoidberg_port_"), "_", 2)
 		if len(p) != 2 {
-			log.Printf("invalid port: %s", k)
+			logger.With("label", k).Warnf("invalid port in label")
 			continue
 		}
 
 		if port, err := strconv.Atoi(p[0]); err != nil {
-			log.Printf("invalid port: %s", k)
+			logger.With("label", k).Warnf("invalid port in label")
 			continue
 		} else {
 			if _, ok := r[port]; !ok {
@@ -1,9 +1,10 @@
 package application
 
 import (
+	"context"
 	"errors"
 	"flag"
-	"log"
+	"fmt"
 	"os"
 
 	fetcher "github.com/bobrik/zoidberg/marathon"
@@ -22,7 +23,12 @@ func init() {
 			)
 		},
 		Maker: func(balancer string) (Finder, error) {
-			return NewMarathonFinder(*marathonURLFlag, balancer)
+			family, err := addressFamilyFromFlag()
+			if err != nil {
+				return nil, err
+			}
+
+			return NewMarathonFinder(*marathonURLFlag, balancer, family)
 		},
 	})
 }
@@ -30,11 +36,14 @@ func init() {
 // MarathonFinder represents a finder that finds apps in Marathon
 type MarathonFinder struct {
 	fetcher  *fetcher.AppFetcher
+	url      string
 	balancer string
+	family   AddressFamily
 }
 
 // NewMarathonFinder creates a new Marathon Finder with Marathon location
-func NewMarathonFinder(url string, balancer string) (Finder, error) {
+// and preferred address family for container addresses
+func NewMarathonFinder(url string, balancer string, family AddressFamily) (Finder, error) {
 	if len(url) == 0 {
 		return nil, errors.New("empty marathon url for marathon application finder")
 	}
@@ -46,28 +55,50 @@ func NewMarathonFinder(url string, balancer string) (Finder, error) {
 
 	return &MarathonFinder{
 		fetcher:  fetcher,
+		url:      url,
 		balancer: balancer,
+		family:   family,
 	}, nil
 }
 
 // Apps returns our applications running on associated Marathon
-func (m *MarathonFinder) Apps() (Apps, error) {
-	ma, err := m.fetcher.FetchApps(nil)
+func (m *MarathonFinder) Apps(ctx context.Context) (Apps, error) {
+	groups, err := m.GroupApps(ctx, []string{m.balancer})
 	if err != nil {
 		return nil, err
 	}
 
-	apps := map[string]App{}
+	return groups[m.balancer], nil
+}
+
+// Source returns identifier of Marathon the finder talks to
+func (m *MarathonFinder) Source() string {
+	return fmt.Sprintf("marathon:%s:%s", m.family, m.url)
+}
+
+// GroupApps returns applications running on associated Marathon
+// for each of the specified balancers, fetching apps only once
+func (m *MarathonFinder) GroupApps(ctx context.Context, balancers []string) (map[string]Apps, error) {
+	ma, err := m.fetcher.FetchApps(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]Apps, len(balancers))
+	for _, b := range balancers {
+		groups[b] = Apps{}
+	}
 
 	for _, a := range ma {
 		for port, labels := range extractApps(*a.Labels) {
-			if m.balancer != labels["balanced_by"] {
+			apps, ok := groups[labels["balanced_by"]]
+			if !ok {
 				continue
 			}
 
 			name := labels["app_name"]
 			if name == "" {
-				log.Printf("app %s has no label zoidberg_port_%d_app_name", a.ID, port)
+				logger.With("marathon_app", a.ID).Warnf("no label zoidberg_port_%d_app_name", port)
 				continue
 			}
 
@@ -83,8 +114,14 @@ func (m *MarathonFinder) Apps() (Apps, error) {
 				app.Meta = labels
 			}
 
+			mode, err := addressMode(labels)
+			if err != nil {
+				logger.With("app", name, "marathon_app", a.ID).Warnf("invalid label zoidberg_port_%d_address_mode: %s", port, err)
+				continue
+			}
+
 			for _, task := range a.Tasks {
-				server := marathonTaskToServer(task, port, version)
+				server := marathonTaskToServer(task, port, version, mode, m.family, marathonContainerPorts(a))
 				if server != nil {
 					app.Servers = append(app.Servers, *server)
 				}
@@ -94,12 +131,30 @@ func (m *MarathonFinder) Apps() (Apps, error) {
 		}
 	}
 
-	return apps, nil
+	return groups, nil
 }
 
-func marathonTaskToServer(task *marathon.Task, port int, version string) *Server {
-	if port >= len(task.Ports) {
-		log.Printf("task %s does not have expected port %d", task.ID, port)
+func marathonTaskToServer(task *marathon.Task, port int, version string, mode string, family AddressFamily, containerPorts []int) *Server {
+	host, ports := task.Host, task.Ports
+	if mode == addressModeContainer {
+		if len(task.IPAddresses) == 0 {
+			logger.With("task", task.ID).Warnf("no container ip address")
+			return nil
+		}
+
+		addresses := make([]string, 0, len(task.IPAddresses))
+		for _, ip := range task.IPAddresses {
+			addresses = append(addresses, ip.IPAddress)
+		}
+
+		host = family.pick(addresses)
+		if len(containerPorts) > 0 {
+			ports = containerPorts
+		}
+	}
+
+	if port >= len(ports) {
+		logger.With("task", task.ID).Warnf("no expected port %d", port)
 		return nil
 	}
 
@@ -121,8 +176,26 @@ func marathonTaskToServer(task *marathon.Task, port int, version string) *Server
 
 	return &Server{
 		Version: version,
-		Host:    task.Host,
-		Port:    task.Ports[port],
-		Ports:   task.Ports,
+		Host:    host,
+		Port:    ports[port],
+		Ports:   ports,
+	}
+}
+
+// marathonContainerPorts returns ports that app listens on inside of
+// the container from container port mappings or ip-per-task discovery
+func marathonContainerPorts(app fetcher.App) []int {
+	ports := []int{}
+
+	for _, m := range app.PortMappings {
+		ports = append(ports, m.ContainerPort)
 	}
+
+	if len(ports) == 0 && app.IPAddressPerTask != nil && app.IPAddressPerTask.Discovery != nil && app.IPAddressPerTask.Discovery.Ports != nil {
+		for _, p := range *app.IPAddressPerTask.Discovery.Ports {
+			ports = append(ports, p.Number)
+		}
+	}
+
+	return ports
 }
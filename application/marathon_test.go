@@ -0,0 +1,77 @@
+package application
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestMarathonFinderContainerPorts(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"apps":[
+			{
+				"id": "/mesos",
+				"labels": {
+					"zoidberg_port_0_balanced_by": "lb",
+					"zoidberg_port_0_app_name": "mesos",
+					"zoidberg_port_0_address_mode": "container",
+					"zoidberg_port_1_balanced_by": "lb",
+					"zoidberg_port_1_app_name": "mesos-admin",
+					"zoidberg_port_1_address_mode": "container"
+				},
+				"container": {"type": "MESOS", "portMappings": [{"containerPort": 8080}, {"containerPort": 9090}]},
+				"tasks": [{"id": "mesos.1", "host": "h1", "ports": [31000, 31001], "ipAddresses": [{"ipAddress": "10.0.0.1", "protocol": "IPv4"}]}]
+			},
+			{
+				"id": "/docker",
+				"labels": {
+					"zoidberg_port_0_balanced_by": "lb",
+					"zoidberg_port_0_app_name": "docker",
+					"zoidberg_port_0_address_mode": "container"
+				},
+				"container": {"type": "DOCKER", "docker": {"image": "nginx", "portMappings": [{"containerPort": 80}]}},
+				"tasks": [{"id": "docker.1", "host": "h2", "ports": [31002], "ipAddresses": [{"ipAddress": "10.0.0.2", "protocol": "IPv4"}]}]
+			},
+			{
+				"id": "/host",
+				"labels": {
+					"zoidberg_port_0_balanced_by": "lb",
+					"zoidberg_port_0_app_name": "host"
+				},
+				"container": {"type": "MESOS", "portMappings": [{"containerPort": 8080}]},
+				"tasks": [{"id": "host.1", "host": "h3", "ports": [31003], "ipAddresses": [{"ipAddress": "10.0.0.3", "protocol": "IPv4"}]}]
+			}
+		]}`))
+	}))
+
+	defer s.Close()
+
+	f, err := NewMarathonFinder(s.URL, "lb", AddressFamilyAny)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apps, err := f.Apps(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]Server{
+		"mesos":       {{Version: "1", Host: "10.0.0.1", Port: 8080, Ports: []int{8080, 9090}}},
+		"mesos-admin": {{Version: "1", Host: "10.0.0.1", Port: 9090, Ports: []int{8080, 9090}}},
+		"docker":      {{Version: "1", Host: "10.0.0.2", Port: 80, Ports: []int{80}}},
+		"host":        {{Version: "1", Host: "h3", Port: 31003, Ports: []int{31003}}},
+	}
+
+	if len(apps) != len(expected) {
+		t.Fatalf("expected %d apps, got %d", len(expected), len(apps))
+	}
+
+	for name, servers := range expected {
+		if !reflect.DeepEqual(apps[name].Servers, servers) {
+			t.Errorf("app %s: expected servers %v, got %v", name, servers, apps[name].Servers)
+		}
+	}
+}
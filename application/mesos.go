@@ -1,16 +1,21 @@
 package application
 
 import (
+	"context"
 	"errors"
 	"flag"
-	"log"
+	"fmt"
 	"os"
 	"strings"
 
 	"github.com/bobrik/zoidberg/mesos"
 )
 
-var mesosMastersFlag *string
+var (
+	mesosMastersFlag          *string
+	mesosAPIFlag              *string
+	mesosIncludeUncheckedFlag *bool
+)
 
 func init() {
 	RegisterFinderMaker("mesos", FinderMaker{
@@ -20,49 +25,106 @@ func init() {
 				os.Getenv("APPLICATION_FINDER_MESOS_MASTERS"),
 				"mesos masters (http://host:port[,http://host:port]) for mesos application finder",
 			)
+
+			mesosAPIFlag = flag.String(
+				"application-finder-mesos-api",
+				os.Getenv("APPLICATION_FINDER_MESOS_API"),
+				"mesos api (state or v1) for mesos application finder, defaults to state",
+			)
+
+			mesosIncludeUncheckedFlag = flag.Bool(
+				"application-finder-mesos-include-unchecked",
+				os.Getenv("APPLICATION_FINDER_MESOS_INCLUDE_UNCHECKED") == "true",
+				"include tasks with health checks that did not report yet for mesos application finder",
+			)
 		},
 		Maker: func(balancer string) (Finder, error) {
-			return NewMesosFinder(strings.Split(*mesosMastersFlag, ","), balancer)
+			family, err := addressFamilyFromFlag()
+			if err != nil {
+				return nil, err
+			}
+
+			return NewMesosFinder(strings.Split(*mesosMastersFlag, ","), *mesosAPIFlag, balancer, family, *mesosIncludeUncheckedFlag)
 		},
 	})
 }
 
-// MesosFinder represents a finder that finds apps on Mesos
+// MesosFinder represents a finder that finds apps on Mesos, tasks
+// that fail their health checks are skipped
 type MesosFinder struct {
-	fetcher  *mesos.TaskFetcher
-	balancer string
+	fetcher   mesos.Fetcher
+	masters   []string
+	api       string
+	balancer  string
+	family    AddressFamily
+	unchecked bool
 }
 
 // NewMesosFinder creates a new Mesos Finder with Mesos master locations
-func NewMesosFinder(masters []string, balancer string) (*MesosFinder, error) {
+// and api, preferred address family for container addresses and whether
+// to include tasks with health checks that did not report yet
+func NewMesosFinder(masters []string, api, balancer string, family AddressFamily, unchecked bool) (*MesosFinder, error) {
 	if len(masters) == 0 {
 		return nil, errors.New("empty list of masters for mesos balancer finder")
 	}
 
+	fetcher, err := mesos.NewFetcher(masters, api)
+	if err != nil {
+		return nil, err
+	}
+
 	return &MesosFinder{
-		fetcher:  mesos.NewTaskFetcher(masters),
-		balancer: balancer,
+		fetcher:   fetcher,
+		masters:   masters,
+		api:       api,
+		balancer:  balancer,
+		family:    family,
+		unchecked: unchecked,
 	}, nil
 }
 
 // Apps returns our applications running on associated Mesos cluster
-func (m *MesosFinder) Apps() (Apps, error) {
-	tasks, err := m.fetcher.FetchTasks()
+func (m *MesosFinder) Apps(ctx context.Context) (Apps, error) {
+	groups, err := m.GroupApps(ctx, []string{m.balancer})
+	if err != nil {
+		return nil, err
+	}
+
+	return groups[m.balancer], nil
+}
+
+// Source returns identifier of Mesos cluster the finder talks to
+func (m *MesosFinder) Source() string {
+	return fmt.Sprintf("mesos:%s:%s:%t:%s", m.api, m.family, m.unchecked, strings.Join(m.masters, ","))
+}
+
+// GroupApps returns applications running on associated Mesos cluster
+// for each of the specified balancers, fetching tasks only once
+func (m *MesosFinder) GroupApps(ctx context.Context, balancers []string) (map[string]Apps, error) {
+	tasks, err := m.fetcher.FetchTasks(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	apps := map[string]App{}
+	groups := make(map[string]Apps, len(balancers))
+	for _, b := range balancers {
+		groups[b] = Apps{}
+	}
 
 	for _, task := range tasks {
+		if !m.healthy(task) {
+			continue
+		}
+
 		for port, labels := range extractApps(task.Labels) {
-			if m.balancer != labels["balanced_by"] {
+			apps, ok := groups[labels["balanced_by"]]
+			if !ok {
 				continue
 			}
 
 			name := labels["app_name"]
 			if name == "" {
-				log.Printf("task %s has no label zoidberg_port_%d_app_version", task.Name, port)
+				logger.With("task", task.Name).Warnf("no label zoidberg_port_%d_app_name", port)
 				continue
 			}
 
@@ -78,21 +140,48 @@ func (m *MesosFinder) Apps() (Apps, error) {
 				app.Meta = labels
 			}
 
-			if port >= len(task.Ports) {
-				log.Printf("task %s does not have expected port %d", task.Name, port)
+			mode, err := addressMode(labels)
+			if err != nil {
+				logger.With("app", name, "task", task.Name).Warnf("invalid label zoidberg_port_%d_address_mode: %s", port, err)
+				continue
+			}
+
+			host, ports := task.Host, task.Ports
+			if mode == addressModeContainer {
+				if len(task.IPAddresses) == 0 {
+					logger.With("app", name, "task", task.Name).Warnf("no container ip address")
+					continue
+				}
+
+				host, ports = m.family.pick(task.IPAddresses), task.ContainerPorts
+			}
+
+			if port >= len(ports) {
+				logger.With("app", name, "task", task.Name).Warnf("no expected port %d", port)
 				continue
 			}
 
 			app.Servers = append(app.Servers, Server{
 				Version: version,
-				Host:    task.Host,
-				Port:    task.Ports[port],
-				Ports:   task.Ports,
+				Host:    host,
+				Port:    ports[port],
+				Ports:   ports,
 			})
 
 			apps[name] = app
 		}
 	}
 
-	return apps, nil
+	return groups, nil
+}
+
+// healthy returns whether task passes its health check, tasks without
+// health checks are healthy and tasks with health checks that did not
+// report yet are only healthy if finder includes them
+func (m *MesosFinder) healthy(task mesos.Task) bool {
+	if task.Healthy != nil {
+		return *task.Healthy
+	}
+
+	return !task.HealthChecked || m.unchecked
 }
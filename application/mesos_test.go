@@ -0,0 +1,47 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/bobrik/zoidberg/mesos"
+)
+
+func TestMesosFinderHealthy(t *testing.T) {
+	yes, no := true, false
+
+	table := []struct {
+		task      mesos.Task
+		unchecked bool
+		healthy   bool
+	}{
+		{
+			task:    mesos.Task{},
+			healthy: true,
+		},
+		{
+			task:    mesos.Task{HealthChecked: true},
+			healthy: false,
+		},
+		{
+			task:      mesos.Task{HealthChecked: true},
+			unchecked: true,
+			healthy:   true,
+		},
+		{
+			task:    mesos.Task{HealthChecked: true, Healthy: &yes},
+			healthy: true,
+		},
+		{
+			task:      mesos.Task{HealthChecked: true, Healthy: &no},
+			unchecked: true,
+			healthy:   false,
+		},
+	}
+
+	for i, row := range table {
+		m := &MesosFinder{unchecked: row.unchecked}
+		if healthy := m.healthy(row.task); healthy != row.healthy {
+			t.Errorf("row %d: expected: %v, got: %v", i, row.healthy, healthy)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	multiFindersFlag  *string
+	multiStaleTTLFlag *time.Duration
+)
+
+func init() {
+	RegisterFinderMaker("multi", FinderMaker{
+		Flags: func() {
+			multiFindersFlag = flag.String(
+				"application-finder-multi-finders",
+				os.Getenv("APPLICATION_FINDER_MULTI_FINDERS"),
+				"application finders to merge for multi application finder (finder[,finder]), earlier ones take precedence for meta",
+			)
+
+			multiStaleTTLFlag = flag.Duration(
+				"application-finder-multi-stale-ttl",
+				time.Minute*5,
+				"time to use the last good apps of a failing source for multi application finder, 0 disables",
+			)
+		},
+		Maker: func(balancer string) (Finder, error) {
+			names := []string{}
+			finders := []Finder{}
+
+			for _, name := range strings.Split(*multiFindersFlag, ",") {
+				name = strings.TrimSpace(name)
+				if name == "" {
+					continue
+				}
+
+				if name == "multi" {
+					return nil, errors.New("multi application finder can't include itself")
+				}
+
+				f, err := FinderByName(name, balancer)
+				if err != nil {
+					return nil, err
+				}
+
+				names = append(names, name)
+				finders = append(finders, f)
+			}
+
+			return NewMultiFinder(names, finders, balancer, *multiStaleTTLFlag)
+		},
+	})
+}
+
+// MultiFinder represents a finder that merges apps of several finders,
+// servers of apps with the same name are concatenated and meta of
+// earlier finders takes precedence on conflicts
+type MultiFinder struct {
+	names    []string
+	finders  []Finder
+	balancer string
+	staleTTL time.Duration
+	state    *multiState
+}
+
+// multiState keeps the last good apps of finders of multi finders,
+// it is shared by multi finders with the same source, so statuses
+// are available for every balancer group
+type multiState struct {
+	sources []*multiSource
+	mutex   sync.Mutex
+}
+
+// multiSource is a finder of multi finder with its last good apps
+type multiSource struct {
+	name         string
+	groups       map[string]Apps
+	lastSuccess  time.Time
+	failingSince time.Time
+	err          error
+}
+
+// multiStates are states of multi finders by their source
+var (
+	multiStates      = map[string]*multiState{}
+	multiStatesMutex sync.Mutex
+)
+
+// NewMultiFinder creates a new multi finder from named finders, apps
+// of a failing finder are used for up to staleTTL since its last success
+func NewMultiFinder(names []string, finders []Finder, balancer string, staleTTL time.Duration) (*MultiFinder, error) {
+	if len(finders) == 0 {
+		return nil, errors.New("no finders specified for multi application finder")
+	}
+
+	if len(names) != len(finders) {
+		return nil, errors.New("expected a name for every finder of multi application finder")
+	}
+
+	m := &MultiFinder{
+		names:    names,
+		finders:  finders,
+		balancer: balancer,
+		staleTTL: staleTTL,
+	}
+
+	multiStatesMutex.Lock()
+	defer multiStatesMutex.Unlock()
+
+	m.state = multiStates[m.Source()]
+	if m.state == nil {
+		m.state = &multiState{
+			sources: make([]*multiSource, len(names)),
+		}
+
+		for i, name := range names {
+			m.state.sources[i] = &multiSource{
+				name: name,
+			}
+		}
+
+		multiStates[m.Source()] = m.state
+	}
+
+	return m, nil
+}
+
+// Apps returns merged applications of all finders
+func (m *MultiFinder) Apps(ctx context.Context) (Apps, error) {
+	groups, err := m.GroupApps(ctx, []string{m.balancer})
+	if err != nil {
+		return nil, err
+	}
+
+	return groups[m.balancer], nil
+}
+
+// Source returns sources of all finders, finders that are not
+// group finders only find apps of the balancer of multi finder
+func (m *MultiFinder) Source() string {
+	sources := make([]string, len(m.finders))
+	for i, f := range m.finders {
+		if gf, ok := f.(GroupFinder); ok {
+			sources[i] = gf.Source()
+		} else {
+			sources[i] = m.names[i] + ":group:" + m.balancer
+		}
+	}
+
+	return "multi:" + strings.Join(sources, ",")
+}
+
+// GroupApps returns merged applications of all finders for each
+// of the specified balancers, every finder is only called once
+func (m *MultiFinder) GroupApps(ctx context.Context, balancers []string) (map[string]Apps, error) {
+	results := make([]map[string]Apps, len(m.finders))
+	errs := make([]error, len(m.finders))
+
+	wg := sync.WaitGroup{}
+	for i, f := range m.finders {
+		wg.Add(1)
+		go func(i int, f Finder) {
+			defer wg.Done()
+			results[i], errs[i] = m.find(ctx, f, balancers)
+		}(i, f)
+	}
+
+	wg.Wait()
+
+	m.state.mutex.Lock()
+	defer m.state.mutex.Unlock()
+
+	now := time.Now()
+
+	for i, s := range m.state.sources {
+		if errs[i] == nil {
+			if s.groups == nil {
+				s.groups = map[string]Apps{}
+			}
+
+			for _, b := range balancers {
+				s.groups[b] = results[i][b]
+			}
+
+			s.lastSuccess = now
+			s.failingSince = time.Time{}
+			s.err = nil
+			continue
+		}
+
+		if s.failingSince.IsZero() {
+			s.failingSince = now
+		}
+
+		s.err = errs[i]
+
+		if !m.stale(s, now) {
+			return nil, fmt.Errorf("error finding apps of %s: %s", s.name, errs[i])
+		}
+
+		logger.With("source", s.name).Warnf("using apps from %s ago: %s", now.Sub(s.lastSuccess), errs[i])
+	}
+
+	groups := make(map[string]Apps, len(balancers))
+	for _, b := range balancers {
+		apps := Apps{}
+		for _, s := range m.state.sources {
+			mergeApps(apps, s.groups[b])
+		}
+
+		groups[b] = apps
+	}
+
+	return groups, nil
+}
+
+// find returns apps of the finder for each of the specified balancers,
+// finders that are not group finders only have the source of their own
+// balancer, so they are never asked about other balancers
+func (m *MultiFinder) find(ctx context.Context, f Finder, balancers []string) (map[string]Apps, error) {
+	if gf, ok := f.(GroupFinder); ok {
+		return gf.GroupApps(ctx, balancers)
+	}
+
+	apps, err := f.Apps(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]Apps{m.balancer: apps}, nil
+}
+
+// stale returns whether the last good apps of source can still be used
+func (m *MultiFinder) stale(s *multiSource, now time.Time) bool {
+	return s.groups != nil && now.Sub(s.lastSuccess) <= m.staleTTL
+}
+
+// SourceStatuses returns statuses of finders of the multi finder
+func (m *MultiFinder) SourceStatuses() []SourceStatus {
+	m.state.mutex.Lock()
+	defer m.state.mutex.Unlock()
+
+	now := time.Now()
+
+	statuses := make([]SourceStatus, len(m.state.sources))
+	for i, s := range m.state.sources {
+		statuses[i] = SourceStatus{
+			Source: s.name,
+			Apps:   len(s.groups[m.balancer]),
+		}
+
+		if !s.lastSuccess.IsZero() {
+			t := s.lastSuccess
+			statuses[i].LastSuccess = &t
+		}
+
+		if s.err != nil {
+			t := s.failingSince
+			statuses[i].FailingSince = &t
+			statuses[i].Error = s.err.Error()
+			statuses[i].Stale = m.stale(s, now)
+		}
+	}
+
+	return statuses
+}
+
+// Close closes finders that hold resources, like watched files
+func (m *MultiFinder) Close() error {
+	var err error
+
+	for i, f := range m.finders {
+		c, ok := f.(io.Closer)
+		if !ok {
+			continue
+		}
+
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("error closing %s: %s", m.names[i], cerr)
+		}
+	}
+
+	return err
+}
+
+// mergeApps adds apps to the merged apps, servers are concatenated
+// and existing meta keys are kept
+func mergeApps(merged, apps Apps) {
+	for name, app := range apps {
+		m, ok := merged[name]
+		if !ok {
+			m = App{
+				Name:    app.Name,
+				Servers: []Server{},
+				Meta:    map[string]string{},
+			}
+		}
+
+		m.Servers = append(m.Servers, app.Servers...)
+
+		for k, v := range app.Meta {
+			if _, ok := m.Meta[k]; !ok {
+				m.Meta[k] = v
+			}
+		}
+
+		merged[name] = m
+	}
+}
@@ -0,0 +1,219 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeFinder returns preset apps or error
+type fakeFinder struct {
+	apps Apps
+	err  error
+}
+
+func (f *fakeFinder) Apps(ctx context.Context) (Apps, error) {
+	return f.apps, f.err
+}
+
+func TestMultiFinderMerge(t *testing.T) {
+	a := &fakeFinder{
+		apps: Apps{
+			"web": {
+				Name:    "web",
+				Servers: []Server{{Host: "a", Port: 1, Ports: []int{1}, Version: "1"}},
+				Meta:    map[string]string{"owner": "a"},
+			},
+		},
+	}
+
+	b := &fakeFinder{
+		apps: Apps{
+			"web": {
+				Name:    "web",
+				Servers: []Server{{Host: "b", Port: 2, Ports: []int{2}, Version: "1"}},
+				Meta:    map[string]string{"owner": "b", "region": "b"},
+			},
+			"api": {
+				Name:    "api",
+				Servers: []Server{{Host: "b", Port: 3, Ports: []int{3}, Version: "2"}},
+			},
+		},
+	}
+
+	m, err := NewMultiFinder([]string{"a", "b"}, []Finder{a, b}, "merge", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apps, err := m.Apps(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Apps{
+		"web": {
+			Name: "web",
+			Servers: []Server{
+				{Host: "a", Port: 1, Ports: []int{1}, Version: "1"},
+				{Host: "b", Port: 2, Ports: []int{2}, Version: "1"},
+			},
+			Meta: map[string]string{"owner": "a", "region": "b"},
+		},
+		"api": {
+			Name:    "api",
+			Servers: []Server{{Host: "b", Port: 3, Ports: []int{3}, Version: "2"}},
+			Meta:    map[string]string{},
+		},
+	}
+
+	if !reflect.DeepEqual(apps, expected) {
+		t.Errorf("expected: %#v, got: %#v", expected, apps)
+	}
+
+	if len(a.apps["web"].Servers) != 1 {
+		t.Errorf("expected source apps not to be modified, got: %v", a.apps["web"].Servers)
+	}
+}
+
+func TestMultiFinderStale(t *testing.T) {
+	a := &fakeFinder{
+		apps: Apps{"web": {Name: "web", Servers: []Server{{Host: "a", Port: 1}}}},
+	}
+
+	b := &fakeFinder{
+		apps: Apps{"api": {Name: "api", Servers: []Server{{Host: "b", Port: 1}}}},
+	}
+
+	m, err := NewMultiFinder([]string{"a", "b"}, []Finder{a, b}, "stale", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = m.Apps(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	b.err = errors.New("down")
+
+	apps, err := m.Apps(context.Background())
+	if err != nil {
+		t.Fatalf("expected last good apps of failing source to be used, got: %s", err)
+	}
+
+	if _, ok := apps["api"]; !ok {
+		t.Errorf("expected api app from the last good result, got: %v", apps)
+	}
+
+	statuses := m.SourceStatuses()
+	if statuses[0].Error != "" || statuses[0].Stale {
+		t.Errorf("expected healthy first source, got: %+v", statuses[0])
+	}
+
+	if statuses[1].Error != "down" || !statuses[1].Stale || statuses[1].FailingSince == nil || statuses[1].Apps != 1 {
+		t.Errorf("expected stale second source, got: %+v", statuses[1])
+	}
+
+	m.state.sources[1].lastSuccess = time.Now().Add(-time.Minute * 2)
+
+	if _, err = m.Apps(context.Background()); err == nil {
+		t.Errorf("expected error after the last good result expired")
+	}
+
+	m, err = NewMultiFinder([]string{"b"}, []Finder{b}, "stale", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = m.Apps(context.Background()); err == nil {
+		t.Errorf("expected error without the last good result")
+	}
+}
+
+func TestMultiFinderGroupApps(t *testing.T) {
+	calls := map[string]*int{"a": new(int), "b": new(int)}
+
+	finders := map[string]Finder{}
+	for _, balancer := range []string{"x", "y"} {
+		a := testGroupFinder{
+			source: "multi-group-a",
+			groups: map[string]Apps{
+				"x": {"web": {Name: "web", Servers: []Server{{Host: "a", Port: 1}}}},
+				"y": {"api": {Name: "api", Servers: []Server{{Host: "a", Port: 2}}}},
+			},
+			calls: calls["a"],
+		}
+
+		b := testGroupFinder{
+			source: "multi-group-b",
+			groups: map[string]Apps{
+				"y": {"api": {Name: "api", Servers: []Server{{Host: "b", Port: 3}}}},
+			},
+			calls: calls["b"],
+		}
+
+		m, err := NewMultiFinder([]string{"a", "b"}, []Finder{a, b}, balancer, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		finders[balancer] = m
+	}
+
+	groups, err := FindGroupApps(context.Background(), finders, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *calls["a"] != 1 || *calls["b"] != 1 {
+		t.Errorf("expected every source to be called once, got: %d and %d", *calls["a"], *calls["b"])
+	}
+
+	if len(groups["x"]) != 1 || len(groups["x"]["web"].Servers) != 1 {
+		t.Errorf("expected web app with one server for x, got: %v", groups["x"])
+	}
+
+	if len(groups["y"]) != 1 || len(groups["y"]["api"].Servers) != 2 {
+		t.Errorf("expected api app with two servers for y, got: %v", groups["y"])
+	}
+
+	// statuses are shared with multi finders of other groups
+	statuses := finders["y"].(*MultiFinder).SourceStatuses()
+	if statuses[0].LastSuccess == nil || statuses[0].Apps != 1 || statuses[1].Apps != 1 {
+		t.Errorf("expected statuses of the second group, got: %+v", statuses)
+	}
+}
+
+// closingFinder counts how many times it is closed
+type closingFinder struct {
+	fakeFinder
+	closed int
+	err    error
+}
+
+func (c *closingFinder) Close() error {
+	c.closed++
+	return c.err
+}
+
+func TestMultiFinderClose(t *testing.T) {
+	a := &closingFinder{err: errors.New("boom")}
+	b := &fakeFinder{}
+	c := &closingFinder{}
+
+	m, err := NewMultiFinder([]string{"file", "fake", "other"}, []Finder{a, b, c}, "close", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Close()
+	if err == nil || err.Error() != "error closing file: boom" {
+		t.Errorf("expected error of the first finder, got: %v", err)
+	}
+
+	if a.closed != 1 || c.closed != 1 {
+		t.Errorf("expected every closer to be closed once, got: %d and %d", a.closed, c.closed)
+	}
+}
@@ -0,0 +1,50 @@
+package zoidberg
+
+import (
+	"net/http"
+
+	"github.com/bobrik/zoidberg/auth"
+)
+
+// SetAuth sets authentication and authorization of mutating
+// api requests, nil allows anyone to make changes
+func (e *Explorer) SetAuth(a *auth.Auth) {
+	e.mutex.Lock()
+	e.auth = a
+	e.mutex.Unlock()
+}
+
+// authorize checks if the client can change all of the apps, no apps
+// means changes that are not specific to apps, identity of the client
+// is returned if the request is allowed, otherwise error is served
+func (e *Explorer) authorize(w http.ResponseWriter, req *http.Request, apps ...string) (string, bool) {
+	e.mutex.Lock()
+	a := e.auth
+	e.mutex.Unlock()
+
+	if a == nil {
+		return "", true
+	}
+
+	identity, err := a.Authenticate(req)
+	if err != nil {
+		logger.With("remote", req.RemoteAddr, "path", req.URL.Path).Warnf("authentication failed: %s", err)
+		a.Challenge(w)
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+		return "", false
+	}
+
+	if len(apps) == 0 {
+		apps = []string{""}
+	}
+
+	for _, app := range apps {
+		if !a.Allowed(identity, app) {
+			logger.With("identity", identity, "app", app, "path", req.URL.Path).Warnf("change is not allowed")
+			http.Error(w, "change is not allowed", http.StatusForbidden)
+			return "", false
+		}
+	}
+
+	return identity, true
+}
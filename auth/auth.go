@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrNoCredentials is returned when request has no credentials
+var ErrNoCredentials = errors.New("no credentials")
+
+// Authenticator authenticates requests with one kind of credentials
+type Authenticator interface {
+	// Authenticate returns identity of the client or ErrNoCredentials
+	// if request has no credentials of this kind
+	Authenticate(req *http.Request) (string, error)
+}
+
+// Auth authenticates clients and authorizes changes of apps
+type Auth struct {
+	authenticators []Authenticator
+	permissions    Permissions
+	basic          bool
+}
+
+// New creates Auth that tries authenticators in order,
+// nil permissions allow authenticated clients to change everything
+func New(authenticators []Authenticator, permissions Permissions) *Auth {
+	a := &Auth{
+		authenticators: authenticators,
+		permissions:    permissions,
+	}
+
+	for _, au := range authenticators {
+		if _, ok := au.(*BasicAuthenticator); ok {
+			a.basic = true
+		}
+	}
+
+	return a
+}
+
+// Authenticate returns identity of the client from the first
+// authenticator that finds credentials in the request
+func (a *Auth) Authenticate(req *http.Request) (string, error) {
+	for _, au := range a.authenticators {
+		identity, err := au.Authenticate(req)
+		if err == ErrNoCredentials {
+			continue
+		}
+
+		return identity, err
+	}
+
+	return "", ErrNoCredentials
+}
+
+// Allowed checks if identity can change the app,
+// empty app means changes that are not specific to apps
+func (a *Auth) Allowed(identity, app string) bool {
+	if a.permissions == nil {
+		return true
+	}
+
+	return a.permissions.Allowed(identity, app)
+}
+
+// Challenge sets headers that ask client to authenticate
+func (a *Auth) Challenge(w http.ResponseWriter) {
+	if a.basic {
+		w.Header().Set("WWW-Authenticate", `Basic realm="zoidberg"`)
+	}
+}
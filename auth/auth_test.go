@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPermissionsAllowed(t *testing.T) {
+	p := Permissions{
+		"admin":  {"*"},
+		"team-x": {"team-x-*", "shared"},
+	}
+
+	table := []struct {
+		identity string
+		app      string
+		allowed  bool
+	}{
+		{identity: "admin", app: "anything", allowed: true},
+		{identity: "admin", app: "", allowed: true},
+		{identity: "team-x", app: "team-x-api", allowed: true},
+		{identity: "team-x", app: "shared", allowed: true},
+		{identity: "team-x", app: "shared-db", allowed: false},
+		{identity: "team-x", app: "team-y-api", allowed: false},
+		{identity: "team-x", app: "", allowed: false},
+		{identity: "nobody", app: "team-x-api", allowed: false},
+	}
+
+	for _, row := range table {
+		allowed := p.Allowed(row.identity, row.app)
+		if allowed != row.allowed {
+			t.Errorf("%s changing %q: expected: %v, got: %v", row.identity, row.app, row.allowed, allowed)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zoidberg-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	hash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := filepath.Join(dir, "tokens")
+	basic := filepath.Join(dir, "basic")
+
+	err = ioutil.WriteFile(tokens, []byte("# deploys\ns3cr3t deployer\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ioutil.WriteFile(basic, []byte("alice:"+hash+"\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ta, err := NewTokenAuthenticator(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ba, err := NewBasicAuthenticator(basic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := New([]Authenticator{ta, ba, TLSAuthenticator{}}, nil)
+
+	table := []struct {
+		header   string
+		identity string
+		ok       bool
+	}{
+		{header: "Bearer s3cr3t", identity: "deployer", ok: true},
+		{header: "Bearer wrong", ok: false},
+		{header: "Basic YWxpY2U6c2VjcmV0", identity: "alice", ok: true},
+		{header: "Basic YWxpY2U6d3Jvbmc=", ok: false},
+		{header: "", ok: false},
+	}
+
+	for _, row := range table {
+		req, _ := http.NewRequest("PUT", "/versions/app", nil)
+		if row.header != "" {
+			req.Header.Set("Authorization", row.header)
+		}
+
+		identity, err := a.Authenticate(req)
+		if (err == nil) != row.ok || identity != row.identity {
+			t.Errorf("%q: expected: %q (ok=%v), got: %q (%v)", row.header, row.identity, row.ok, identity, err)
+		}
+	}
+}
+
+func TestBasicAuthenticatorHashes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zoidberg-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	valid := filepath.Join(dir, "valid")
+
+	// $2y$ is the prefix htpasswd -B uses for bcrypt hashes
+	err = ioutil.WriteFile(valid, []byte("bob:$2y$04$dZXOcSdk94Iu48nR82GJZ.esKGBf/Qlt4i1M7dGLFEMxqU6PyrYba\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ba, err := NewBasicAuthenticator(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("PUT", "/versions/app", nil)
+	req.SetBasicAuth("bob", "hunter2")
+
+	identity, err := ba.Authenticate(req)
+	if err != nil || identity != "bob" {
+		t.Errorf("expected: %q, got: %q (%v)", "bob", identity, err)
+	}
+
+	invalid := filepath.Join(dir, "invalid")
+
+	err = ioutil.WriteFile(invalid, []byte("\ncarol:hunter2\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewBasicAuthenticator(invalid)
+	if err == nil {
+		t.Fatalf("expected error for plain text password")
+	}
+
+	if !strings.HasPrefix(err.Error(), invalid+":2: expected bcrypt password hash") {
+		t.Errorf("unexpected error: %q", err)
+	}
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BasicAuthenticator authenticates requests with http basic auth,
+// passwords are kept as bcrypt hashes like the ones htpasswd -B makes
+type BasicAuthenticator struct {
+	hashes map[string][]byte
+}
+
+// NewBasicAuthenticator reads password hashes from the file
+// with lines in "<identity>:<hash>" format
+func NewBasicAuthenticator(file string) (*BasicAuthenticator, error) {
+	b := &BasicAuthenticator{
+		hashes: map[string][]byte{},
+	}
+
+	err := readLines(file, func(line string) error {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return errors.New("expected identity and password hash")
+		}
+
+		_, err := bcrypt.Cost([]byte(parts[1]))
+		if err != nil {
+			return fmt.Errorf("expected bcrypt password hash: %s", err)
+		}
+
+		b.hashes[parts[0]] = []byte(parts[1])
+
+		return nil
+	})
+
+	return b, err
+}
+
+// Authenticate implements Authenticator
+func (b *BasicAuthenticator) Authenticate(req *http.Request) (string, error) {
+	identity, password, ok := req.BasicAuth()
+	if !ok {
+		return "", ErrNoCredentials
+	}
+
+	hash, ok := b.hashes[identity]
+	if !ok || bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil {
+		return "", errors.New("invalid user or password")
+	}
+
+	return identity, nil
+}
+
+// HashPassword returns a bcrypt hash of the password with a random salt
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// readLines calls f for every non-empty line of the file that is not
+// a comment, errors from f are reported with file name and line number
+func readLines(file string, f func(line string) error) error {
+	fd, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+
+	defer fd.Close()
+
+	s := bufio.NewScanner(fd)
+	n := 0
+	for s.Scan() {
+		n++
+
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		err = f(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %s", file, n, err)
+		}
+	}
+
+	return s.Err()
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+)
+
+// Permissions map identities to patterns of apps they can change,
+// pattern ending with "*" matches apps by prefix, sole "*" also
+// allows changes that are not specific to apps
+type Permissions map[string][]string
+
+// ReadPermissions reads permissions from the file with lines
+// in "<identity> <pattern>[,<pattern>]" format
+func ReadPermissions(file string) (Permissions, error) {
+	p := Permissions{}
+
+	err := readLines(file, func(line string) error {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return errors.New("expected identity and app patterns")
+		}
+
+		p[fields[0]] = append(p[fields[0]], strings.Split(fields[1], ",")...)
+
+		return nil
+	})
+
+	return p, err
+}
+
+// Allowed checks if identity can change the app,
+// empty app means changes that are not specific to apps
+func (p Permissions) Allowed(identity, app string) bool {
+	for _, pattern := range p[identity] {
+		if pattern == "*" {
+			return true
+		}
+
+		if app == "" {
+			continue
+		}
+
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(app, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+
+			continue
+		}
+
+		if pattern == app {
+			return true
+		}
+	}
+
+	return false
+}
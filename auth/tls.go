@@ -0,0 +1,18 @@
+package auth
+
+import (
+	"net/http"
+)
+
+// TLSAuthenticator authenticates requests with client certificates
+// verified by the server, common name of the subject is the identity
+type TLSAuthenticator struct{}
+
+// Authenticate implements Authenticator
+func (TLSAuthenticator) Authenticate(req *http.Request) (string, error) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return "", ErrNoCredentials
+	}
+
+	return req.TLS.VerifiedChains[0][0].Subject.CommonName, nil
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// TokenAuthenticator authenticates requests with static bearer tokens
+type TokenAuthenticator struct {
+	identities map[[sha256.Size]byte]string
+}
+
+// NewTokenAuthenticator reads tokens from the file
+// with lines in "<token> <identity>" format
+func NewTokenAuthenticator(file string) (*TokenAuthenticator, error) {
+	t := &TokenAuthenticator{
+		identities: map[[sha256.Size]byte]string{},
+	}
+
+	err := readLines(file, func(line string) error {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return errors.New("expected token and identity")
+		}
+
+		t.identities[sha256.Sum256([]byte(fields[0]))] = fields[1]
+
+		return nil
+	})
+
+	return t, err
+}
+
+// Authenticate implements Authenticator, tokens are looked up
+// by their hashes to avoid leaking them through timing
+func (t *TokenAuthenticator) Authenticate(req *http.Request) (string, error) {
+	h := req.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return "", ErrNoCredentials
+	}
+
+	identity, ok := t.identities[sha256.Sum256([]byte(strings.TrimPrefix(h, "Bearer ")))]
+	if !ok {
+		return "", errors.New("invalid bearer token")
+	}
+
+	return identity, nil
+}
@@ -2,19 +2,30 @@ package balancer
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bobrik/zoidberg/application"
 	"github.com/bobrik/zoidberg/state"
 )
 
-// Balancer represents a load balancer
+// updateErrorBody is how much of the body of failed updates is reported
+const updateErrorBody = 512
+
+// Balancer represents a load balancer, updates are sent
+// over http unless scheme says otherwise
 type Balancer struct {
-	Host string `json:"host"`
-	Port int    `json:"port"`
+	Host   string `json:"host"`
+	Port   int    `json:"port"`
+	Scheme string `json:"scheme,omitempty"`
 }
 
 // State represents load balancer's state:
@@ -25,7 +36,7 @@ type State struct {
 }
 
 // Update updates load balancer's state
-func (b Balancer) Update(name string, apps application.Apps, state state.State) error {
+func (b Balancer) Update(ctx context.Context, name string, apps application.Apps, state state.State) error {
 	body, err := json.Marshal(State{
 		Apps:  apps,
 		State: state,
@@ -39,16 +50,38 @@ func (b Balancer) Update(name string, apps application.Apps, state state.State)
 		Timeout: time.Second * 5,
 	}
 
-	u := fmt.Sprintf("http://%s:%d/state/%s", b.Host, b.Port, name)
-	resp, err := c.Post(u, "application/json", bytes.NewReader(body))
+	scheme := b.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	u := fmt.Sprintf("%s://%s/state/%s", scheme, b, name)
+	req, err := http.NewRequest("POST", u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.Do(req.WithContext(ctx))
 	if err != nil {
 		return err
 	}
 
-	return resp.Body.Close()
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// only the beginning of the body is reported, it can be a whole page
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, updateErrorBody))
+		return fmt.Errorf("unexpected response code %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+
+	return err
 }
 
 // String returns load balancer's location string representation
 func (b Balancer) String() string {
-	return fmt.Sprintf("%s:%d", b.Host, b.Port)
+	return net.JoinHostPort(b.Host, strconv.Itoa(b.Port))
 }
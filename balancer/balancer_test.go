@@ -0,0 +1,75 @@
+package balancer
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bobrik/zoidberg/application"
+	"github.com/bobrik/zoidberg/state"
+)
+
+func TestBalancerUpdate(t *testing.T) {
+	table := []struct {
+		code int
+		body string
+		err  string
+	}{
+		{
+			code: http.StatusOK,
+		},
+		{
+			code: http.StatusNoContent,
+		},
+		{
+			code: http.StatusInternalServerError,
+			body: "state is locked\n",
+			err:  "unexpected response code 500: state is locked",
+		},
+		{
+			code: http.StatusBadRequest,
+			body: strings.Repeat("x", updateErrorBody*2),
+			err:  "unexpected response code 400: " + strings.Repeat("x", updateErrorBody),
+		},
+	}
+
+	for i, row := range table {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path != "/state/lb" {
+				t.Errorf("row %d: unexpected path: %s", i, req.URL.Path)
+			}
+
+			w.WriteHeader(row.code)
+			w.Write([]byte(row.body))
+		}))
+
+		host, port, err := net.SplitHostPort(strings.TrimPrefix(s.URL, "http://"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = Balancer{Host: host, Port: p}.Update(context.Background(), "lb", application.Apps{}, state.State{})
+		s.Close()
+
+		if row.err == "" {
+			if err != nil {
+				t.Errorf("row %d: unexpected error: %s", i, err)
+			}
+
+			continue
+		}
+
+		if err == nil || err.Error() != row.err {
+			t.Errorf("row %d: expected error: %q, got: %v", i, row.err, err)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bobrik/zoidberg/dns"
+)
+
+var (
+	dnsFinderNameFlag     *string
+	dnsFinderPortFlag     *string
+	dnsFinderResolverFlag *string
+	dnsFinderStaleTTLFlag *time.Duration
+)
+
+func init() {
+	RegisterFinderMaker("dns", FinderMaker{
+		Flags: func() {
+			dnsFinderNameFlag = flag.String(
+				"balancer-finder-dns-name",
+				os.Getenv("BALANCER_FINDER_DNS_NAME"),
+				"dns name with srv records of balancers or with a/aaaa records if port is set for dns balancer finder",
+			)
+
+			dnsFinderPortFlag = flag.String(
+				"balancer-finder-dns-port",
+				os.Getenv("BALANCER_FINDER_DNS_PORT"),
+				"port of balancers to use with a/aaaa records instead of srv records for dns balancer finder",
+			)
+
+			dnsFinderResolverFlag = flag.String(
+				"balancer-finder-dns-resolver",
+				os.Getenv("BALANCER_FINDER_DNS_RESOLVER"),
+				"dns server (host[:port]) for dns balancer finder, defaults to the first nameserver in /etc/resolv.conf",
+			)
+
+			dnsFinderStaleTTLFlag = flag.Duration(
+				"balancer-finder-dns-stale-ttl",
+				time.Minute*5,
+				"time to use the last good answer after it expires if resolution fails for dns balancer finder, 0 disables",
+			)
+		},
+		Maker: func(balancer string) (Finder, error) {
+			port := 0
+			if *dnsFinderPortFlag != "" {
+				p, err := strconv.Atoi(*dnsFinderPortFlag)
+				if err != nil {
+					return nil, fmt.Errorf("invalid dns balancer finder port: %s", err)
+				}
+
+				port = p
+			}
+
+			return NewDNSFinder(*dnsFinderResolverFlag, *dnsFinderNameFlag, port, balancer, *dnsFinderStaleTTLFlag)
+		},
+	})
+}
+
+// DNSFinder represents a finder that resolves balancers from srv records
+// or from a/aaaa records with a fixed port, answers are cached for
+// their ttl and the last good answer is used if resolution fails
+// for up to stale ttl after it expires
+type DNSFinder struct {
+	client    *dns.Client
+	name      string
+	port      int
+	balancer  string
+	staleTTL  time.Duration
+	balancers []Balancer
+	expires   time.Time
+	mutex     sync.Mutex
+}
+
+// NewDNSFinder creates a new dns finder, srv records of the name
+// are resolved if port is zero
+func NewDNSFinder(resolver, name string, port int, balancer string, staleTTL time.Duration) (*DNSFinder, error) {
+	if name == "" {
+		return nil, errors.New("empty name for dns balancer finder")
+	}
+
+	if port < 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid port %d for dns balancer finder", port)
+	}
+
+	c, err := dns.NewClient(resolver)
+	if err != nil {
+		return nil, fmt.Errorf("error creating dns client: %s", err)
+	}
+
+	return &DNSFinder{
+		client:   c,
+		name:     name,
+		port:     port,
+		balancer: balancer,
+		staleTTL: staleTTL,
+	}, nil
+}
+
+// Name returns the name of the balancer group
+func (d *DNSFinder) Name() string {
+	return d.balancer
+}
+
+// Balancers returns balancers from the cached answer or resolves them again
+func (d *DNSFinder) Balancers(ctx context.Context) ([]Balancer, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := time.Now()
+	if d.balancers != nil && now.Before(d.expires) {
+		return d.balancers, nil
+	}
+
+	var balancers []Balancer
+	var ttl uint32
+	var err error
+
+	if d.port == 0 {
+		balancers, ttl, err = d.resolveSRV(ctx)
+	} else {
+		balancers, ttl, err = d.resolveAddresses(ctx)
+	}
+
+	if err != nil {
+		if d.balancers == nil {
+			return nil, err
+		}
+
+		if now.Sub(d.expires) > d.staleTTL {
+			// balancers that are gone for long shouldn't get updates
+			d.balancers = nil
+			return nil, fmt.Errorf("%s, the last good answer expired %s ago", err, now.Sub(d.expires))
+		}
+
+		logger.With("name", d.name).Warnf("using the last good answer that expired %s ago: %s", now.Sub(d.expires), err)
+
+		return d.balancers, nil
+	}
+
+	sort.Slice(balancers, func(i, j int) bool {
+		return balancers[i].String() < balancers[j].String()
+	})
+
+	d.balancers = balancers
+	d.expires = now.Add(time.Duration(ttl) * time.Second)
+
+	return balancers, nil
+}
+
+// resolveSRV returns balancers from srv records with the lowest ttl,
+// addresses of targets are taken from additional records if present
+func (d *DNSFinder) resolveSRV(ctx context.Context) ([]Balancer, uint32, error) {
+	m, err := d.client.Query(ctx, d.name, dns.TypeSRV)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	addresses := map[string][]dns.Record{}
+	for _, r := range m.Additionals {
+		if r.Type == dns.TypeA || r.Type == dns.TypeAAAA {
+			addresses[r.Name] = append(addresses[r.Name], r)
+		}
+	}
+
+	balancers := []Balancer{}
+	ttl := uint32(0)
+
+	for _, r := range m.Answers {
+		if r.Type != dns.TypeSRV {
+			continue
+		}
+
+		ttl = minTTL(ttl, r.TTL, len(balancers) == 0)
+
+		if len(addresses[r.Target]) == 0 {
+			balancers = append(balancers, Balancer{
+				Host: strings.TrimSuffix(r.Target, "."),
+				Port: int(r.Port),
+			})
+
+			continue
+		}
+
+		for _, a := range addresses[r.Target] {
+			ttl = minTTL(ttl, a.TTL, false)
+
+			balancers = append(balancers, Balancer{
+				Host: a.IP.String(),
+				Port: int(r.Port),
+			})
+		}
+	}
+
+	if len(balancers) == 0 {
+		return nil, 0, fmt.Errorf("no srv records for %s", d.name)
+	}
+
+	return balancers, ttl, nil
+}
+
+// resolveAddresses returns balancers from a and aaaa records
+// with the configured port and the lowest ttl
+func (d *DNSFinder) resolveAddresses(ctx context.Context) ([]Balancer, uint32, error) {
+	balancers := []Balancer{}
+	ttl := uint32(0)
+
+	for _, t := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		m, err := d.client.Query(ctx, d.name, t)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, r := range m.Answers {
+			if r.Type != t {
+				continue
+			}
+
+			ttl = minTTL(ttl, r.TTL, len(balancers) == 0)
+
+			balancers = append(balancers, Balancer{
+				Host: r.IP.String(),
+				Port: d.port,
+			})
+		}
+	}
+
+	if len(balancers) == 0 {
+		return nil, 0, fmt.Errorf("no a or aaaa records for %s", d.name)
+	}
+
+	return balancers, ttl, nil
+}
+
+// minTTL returns the lowest of ttls, the current one is ignored if first
+func minTTL(current, ttl uint32, first bool) uint32 {
+	if first || ttl < current {
+		return ttl
+	}
+
+	return current
+}
@@ -0,0 +1,227 @@
+package balancer
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bobrik/zoidberg/dns"
+)
+
+// stubDNS answers udp queries from preset records, SERVFAIL
+// is returned for every query when it is failing
+type stubDNS struct {
+	records     map[uint16][]dns.Record
+	additionals []dns.Record
+	failing     bool
+	queries     int
+	mutex       sync.Mutex
+}
+
+func (s *stubDNS) serve(t *testing.T) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		pc.Close()
+	})
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			q, err := dns.Unpack(buf[:n])
+			if err != nil {
+				t.Errorf("error decoding query: %s", err)
+				return
+			}
+
+			s.mutex.Lock()
+			s.queries++
+
+			r := dns.Message{ID: q.ID, Response: true, Questions: q.Questions}
+			if s.failing {
+				r.RCode = 2
+			} else {
+				r.Answers = s.records[q.Questions[0].Type]
+				if q.Questions[0].Type == dns.TypeSRV {
+					r.Additionals = s.additionals
+				}
+			}
+
+			s.mutex.Unlock()
+
+			b, err := r.Pack()
+			if err != nil {
+				t.Errorf("error encoding response: %s", err)
+				return
+			}
+
+			pc.WriteTo(b, addr)
+		}
+	}()
+
+	return pc.LocalAddr().String()
+}
+
+func TestDNSFinderSRV(t *testing.T) {
+	s := &stubDNS{
+		records: map[uint16][]dns.Record{
+			dns.TypeSRV: {
+				{Name: "_lb._tcp.example.com.", Type: dns.TypeSRV, TTL: 60, Port: 80, Target: "lb1.example.com."},
+				{Name: "_lb._tcp.example.com.", Type: dns.TypeSRV, TTL: 60, Port: 8080, Target: "lb2.example.com."},
+			},
+		},
+		additionals: []dns.Record{
+			{Name: "lb1.example.com.", Type: dns.TypeA, TTL: 30, IP: net.ParseIP("10.0.0.1").To4()},
+		},
+	}
+
+	f, err := NewDNSFinder(s.serve(t), "_lb._tcp.example.com", 0, "lb", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balancers, err := f.Balancers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Balancer{
+		{Host: "10.0.0.1", Port: 80},
+		{Host: "lb2.example.com", Port: 8080},
+	}
+
+	if !reflect.DeepEqual(balancers, expected) {
+		t.Errorf("expected: %v, got: %v", expected, balancers)
+	}
+
+	// the answer is cached for the lowest ttl
+	if _, err = f.Balancers(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.queries != 1 {
+		t.Errorf("expected cached answer to be used, got %d queries", s.queries)
+	}
+}
+
+func TestDNSFinderAddresses(t *testing.T) {
+	s := &stubDNS{
+		records: map[uint16][]dns.Record{
+			dns.TypeA: {
+				{Name: "lb.example.com.", Type: dns.TypeA, IP: net.ParseIP("10.0.0.1").To4()},
+			},
+			dns.TypeAAAA: {
+				{Name: "lb.example.com.", Type: dns.TypeAAAA, IP: net.ParseIP("2001:db8::1")},
+			},
+		},
+	}
+
+	f, err := NewDNSFinder(s.serve(t), "lb.example.com", 80, "lb", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balancers, err := f.Balancers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Balancer{
+		{Host: "10.0.0.1", Port: 80},
+		{Host: "2001:db8::1", Port: 80},
+	}
+
+	if !reflect.DeepEqual(balancers, expected) {
+		t.Errorf("expected: %v, got: %v", expected, balancers)
+	}
+
+	// zero ttl answers are resolved again and the last
+	// good answer is used when resolution fails
+	s.mutex.Lock()
+	s.failing = true
+	s.mutex.Unlock()
+
+	balancers, err = f.Balancers(context.Background())
+	if err != nil {
+		t.Fatalf("expected the last good answer, got: %s", err)
+	}
+
+	if !reflect.DeepEqual(balancers, expected) {
+		t.Errorf("expected: %v, got: %v", expected, balancers)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.queries != 3 {
+		t.Errorf("expected zero ttl answer to be resolved again, got %d queries", s.queries)
+	}
+}
+
+func TestDNSFinderStale(t *testing.T) {
+	s := &stubDNS{
+		records: map[uint16][]dns.Record{
+			dns.TypeA: {
+				{Name: "lb.example.com.", Type: dns.TypeA, TTL: 30, IP: net.ParseIP("10.0.0.1").To4()},
+			},
+		},
+	}
+
+	f, err := NewDNSFinder(s.serve(t), "lb.example.com", 80, "lb", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = f.Balancers(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	s.mutex.Lock()
+	s.failing = true
+	s.mutex.Unlock()
+
+	f.expires = time.Now().Add(-time.Second * 30)
+
+	if _, err = f.Balancers(context.Background()); err != nil {
+		t.Errorf("expected the last good answer within stale ttl, got: %s", err)
+	}
+
+	f.expires = time.Now().Add(-time.Minute * 2)
+
+	if _, err = f.Balancers(context.Background()); err == nil {
+		t.Errorf("expected error after the last good answer is stale for too long")
+	}
+
+	f.expires = time.Now().Add(-time.Second * 30)
+
+	if _, err = f.Balancers(context.Background()); err == nil {
+		t.Errorf("expected error after the last good answer is evicted")
+	}
+}
+
+func TestDNSFinderFailure(t *testing.T) {
+	s := &stubDNS{failing: true}
+
+	f, err := NewDNSFinder(s.serve(t), "lb.example.com", 0, "lb", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = f.Balancers(context.Background()); err == nil {
+		t.Errorf("expected error without the last good answer")
+	}
+}
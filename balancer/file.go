@@ -0,0 +1,101 @@
+package balancer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bobrik/zoidberg/watch"
+)
+
+var fileFinderPathFlag *string
+
+func init() {
+	RegisterFinderMaker("file", FinderMaker{
+		Flags: func() {
+			fileFinderPathFlag = flag.String(
+				"balancer-finder-file-path",
+				os.Getenv("BALANCER_FINDER_FILE_PATH"),
+				"file with balancers (host:port[:scheme]) on separate lines for file balancer finder, reloaded on change",
+			)
+		},
+		Maker: func(balancer string) (Finder, error) {
+			return NewFileFinder(*fileFinderPathFlag, balancer)
+		},
+	})
+}
+
+// FileFinder represents a finder that reads balancers from a file,
+// the file is read again when it changes
+type FileFinder struct {
+	file     *watch.File
+	balancer string
+}
+
+// NewFileFinder creates a new file finder for the file
+func NewFileFinder(path, balancer string) (*FileFinder, error) {
+	if path == "" {
+		return nil, errors.New("empty file path for file balancer finder")
+	}
+
+	file, err := watch.NewFile(path, func(b []byte) (interface{}, error) {
+		return parseBalancersFile(b)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileFinder{
+		file:     file,
+		balancer: balancer,
+	}, nil
+}
+
+// Name returns the name of the balancer group
+func (f *FileFinder) Name() string {
+	return f.balancer
+}
+
+// Balancers returns balancers listed in the file
+func (f *FileFinder) Balancers(ctx context.Context) ([]Balancer, error) {
+	v, err := f.file.Value()
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]Balancer), nil
+}
+
+// Close stops watching the file
+func (f *FileFinder) Close() error {
+	return f.file.Close()
+}
+
+// parseBalancersFile parses balancers in host:port[:scheme] format
+// on separate lines, empty lines and lines starting with # are skipped
+func parseBalancersFile(b []byte) ([]Balancer, error) {
+	balancers := []Balancer{}
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		balancer, err := balancerFromString(line)
+		if err != nil {
+			return nil, fmt.Errorf("%d: invalid balancer %q: %s", n, line, err)
+		}
+
+		balancers = append(balancers, balancer)
+	}
+
+	return balancers, s.Err()
+}
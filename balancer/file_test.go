@@ -0,0 +1,111 @@
+package balancer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseBalancersFile(t *testing.T) {
+	table := []struct {
+		input     string
+		balancers []Balancer
+		err       string
+	}{
+		{
+			input: "# static vms\n10.0.0.1:80\n\n  lb.example.com:443:https  \n",
+			balancers: []Balancer{
+				{Host: "10.0.0.1", Port: 80},
+				{Host: "lb.example.com", Port: 443, Scheme: "https"},
+			},
+		},
+		{
+			input:     "",
+			balancers: []Balancer{},
+		},
+		{
+			input: "10.0.0.1:80\n10.0.0.2\n",
+			err:   "2: invalid balancer \"10.0.0.2\": address 10.0.0.2: missing port in address",
+		},
+	}
+
+	for i, row := range table {
+		balancers, err := parseBalancersFile([]byte(row.input))
+		if err != nil {
+			if err.Error() != row.err {
+				t.Errorf("row %d: expected error: %q, got: %q", i, row.err, err)
+			}
+
+			continue
+		}
+
+		if row.err != "" {
+			t.Errorf("row %d: expected error: %q, got nothing", i, row.err)
+			continue
+		}
+
+		if !reflect.DeepEqual(balancers, row.balancers) {
+			t.Errorf("row %d: expected: %v, got: %v", i, row.balancers, balancers)
+		}
+	}
+}
+
+func TestFileFinderReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zoidberg-file-finder")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "balancers")
+
+	write := func(content string, modified time.Time) {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+
+	write("10.0.0.1:80\n", now)
+
+	f, err := NewFileFinder(path, "lb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balancers, err := f.Balancers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(balancers, []Balancer{{Host: "10.0.0.1", Port: 80}}) {
+		t.Errorf("expected one balancer, got: %v", balancers)
+	}
+
+	write("10.0.0.1:80\n10.0.0.2:80\n", now.Add(time.Second))
+
+	balancers, err = f.Balancers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(balancers) != 2 {
+		t.Errorf("expected reloaded file with two balancers, got: %v", balancers)
+	}
+
+	write("10.0.0.3\n", now.Add(time.Second*2))
+
+	if _, err = f.Balancers(context.Background()); err == nil {
+		t.Errorf("expected error for invalid file")
+	}
+}
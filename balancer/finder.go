@@ -1,13 +1,94 @@
 package balancer
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bobrik/zoidberg/logging"
 )
 
+var logger = logging.Component("balancer")
+
 // Finder finds balancers
 type Finder interface {
 	Name() string
-	Balancers() ([]Balancer, error)
+	Balancers(ctx context.Context) ([]Balancer, error)
+}
+
+// GroupFinder is a Finder that can find balancers for several balancer
+// names at once, so cluster state is only fetched once for all of them
+type GroupFinder interface {
+	Finder
+	// Source identifies where balancers come from, finders with
+	// the same source return the same balancers for the same names
+	Source() string
+	// GroupBalancers returns balancers for each of the specified names
+	GroupBalancers(ctx context.Context, balancers []string) (map[string][]Balancer, error)
+}
+
+// FindGroupBalancers returns balancers for each balancer group with its finder,
+// fetching state only once for group finders that share the same source,
+// observe is called after every finder call with its source, finders
+// that are not group finders are identified by their group names
+func FindGroupBalancers(ctx context.Context, finders map[string]Finder, observe func(source string, started time.Time, err error)) (map[string][]Balancer, error) {
+	groups := make(map[string][]Balancer, len(finders))
+
+	sources := map[string]GroupFinder{}
+	balancers := map[string][]string{}
+
+	names := make([]string, 0, len(finders))
+	for b := range finders {
+		names = append(names, b)
+	}
+
+	// the finder of the first group is used for every source
+	sort.Strings(names)
+
+	for _, b := range names {
+		f := finders[b]
+
+		if gf, ok := f.(GroupFinder); ok {
+			s := gf.Source()
+			if _, ok := sources[s]; !ok {
+				sources[s] = gf
+			}
+
+			balancers[s] = append(balancers[s], b)
+			continue
+		}
+
+		started := time.Now()
+		r, err := f.Balancers(ctx)
+		if observe != nil {
+			observe("group:"+b, started, err)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		groups[b] = r
+	}
+
+	for s, gf := range sources {
+		started := time.Now()
+		r, err := gf.GroupBalancers(ctx, balancers[s])
+		if observe != nil {
+			observe(s, started, err)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range balancers[s] {
+			groups[b] = r[b]
+		}
+	}
+
+	return groups, nil
 }
 
 // FinderMaker represents finder maker tuple:
@@ -1,9 +1,9 @@
 package balancer
 
 import (
+	"context"
 	"errors"
 	"flag"
-	"log"
 	"os"
 
 	"github.com/bobrik/zoidberg/marathon"
@@ -29,6 +29,7 @@ func init() {
 // MarathonFinder represents a finder that finds balancers in Marathon
 type MarathonFinder struct {
 	fetcher  *marathon.AppFetcher
+	url      string
 	balancer string
 }
 
@@ -50,6 +51,7 @@ func NewMarathonFinder(url string, balancer string) (*MarathonFinder, error) {
 
 	return &MarathonFinder{
 		fetcher:  fetcher,
+		url:      url,
 		balancer: balancer,
 	}, nil
 }
@@ -60,16 +62,51 @@ func (m *MarathonFinder) Name() string {
 }
 
 // Balancers returns our load balancers running on associated Marathon
-func (m *MarathonFinder) Balancers() ([]Balancer, error) {
-	apps, err := m.fetcher.FetchApps(map[string]string{"zoidberg_balancer_for": m.balancer})
+func (m *MarathonFinder) Balancers(ctx context.Context) ([]Balancer, error) {
+	apps, err := m.fetcher.FetchApps(ctx, map[string]string{"zoidberg_balancer_for": m.balancer})
 	if err != nil {
 		return nil, err
 	}
 
-	balancers := []Balancer{}
+	return marathonBalancers(apps, map[string][]Balancer{m.balancer: {}})[m.balancer], nil
+}
+
+// Source returns identifier of Marathon the finder talks to
+func (m *MarathonFinder) Source() string {
+	return "marathon:" + m.url
+}
+
+// GroupBalancers returns load balancers running on associated Marathon
+// for each of the specified balancer names, fetching apps only once
+func (m *MarathonFinder) GroupBalancers(ctx context.Context, balancers []string) (map[string][]Balancer, error) {
+	apps, err := m.fetcher.FetchAppsWithLabel(ctx, "zoidberg_balancer_for")
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]Balancer, len(balancers))
+	for _, b := range balancers {
+		groups[b] = []Balancer{}
+	}
+
+	return marathonBalancers(apps, groups), nil
+}
+
+// marathonBalancers adds tasks of marathon apps to balancer groups
+func marathonBalancers(apps []marathon.App, groups map[string][]Balancer) map[string][]Balancer {
 	for _, app := range apps {
+		b := ""
+		if app.Labels != nil {
+			b = (*app.Labels)["zoidberg_balancer_for"]
+		}
+
+		balancers, ok := groups[b]
+		if !ok {
+			continue
+		}
+
 		if len(app.Ports) == 0 {
-			log.Printf("app %s has no ports", app.ID)
+			logger.With("balancer", b, "marathon_app", app.ID).Warnf("no ports")
 			continue
 		}
 
@@ -79,7 +116,9 @@ func (m *MarathonFinder) Balancers() ([]Balancer, error) {
 				Port: task.Ports[0],
 			})
 		}
+
+		groups[b] = balancers
 	}
 
-	return balancers, nil
+	return groups
 }
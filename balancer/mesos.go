@@ -1,6 +1,7 @@
 package balancer
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"os"
@@ -9,7 +10,10 @@ import (
 	"github.com/bobrik/zoidberg/mesos"
 )
 
-var mesosFinderMesosMastersFlag *string
+var (
+	mesosFinderMesosMastersFlag *string
+	mesosFinderMesosAPIFlag     *string
+)
 
 func init() {
 	RegisterFinderMaker("mesos", FinderMaker{
@@ -19,9 +23,15 @@ func init() {
 				os.Getenv("BALANCER_FINDER_MESOS_MASTERS"),
 				"mesos masters (http://host:port[,http://host:port]) for mesos balancer finder",
 			)
+
+			mesosFinderMesosAPIFlag = flag.String(
+				"balancer-finder-mesos-api",
+				os.Getenv("BALANCER_FINDER_MESOS_API"),
+				"mesos api (state or v1) for mesos balancer finder, defaults to state",
+			)
 		},
 		Maker: func(balancer string) (Finder, error) {
-			return NewMesosFinder(strings.Split(*mesosFinderMesosMastersFlag, ","), balancer)
+			return NewMesosFinder(strings.Split(*mesosFinderMesosMastersFlag, ","), *mesosFinderMesosAPIFlag, balancer)
 		},
 	})
 }
@@ -29,12 +39,14 @@ func init() {
 // MesosFinder represents a finder that finds apps on Mesos
 type MesosFinder struct {
 	balancer string
-	fetcher  *mesos.TaskFetcher
+	masters  []string
+	api      string
+	fetcher  mesos.Fetcher
 }
 
 // NewMesosFinder creates a new Mesos Finder with
-// Mesos master locations and load balancer name
-func NewMesosFinder(masters []string, balancer string) (*MesosFinder, error) {
+// Mesos master locations, api and load balancer name
+func NewMesosFinder(masters []string, api, balancer string) (*MesosFinder, error) {
 	if len(masters) == 0 {
 		return nil, errors.New("empty list of masters for mesos balancer finder")
 	}
@@ -43,9 +55,16 @@ func NewMesosFinder(masters []string, balancer string) (*MesosFinder, error) {
 		return nil, errors.New("empty balancer name for mesos balancer finder")
 	}
 
+	fetcher, err := mesos.NewFetcher(masters, api)
+	if err != nil {
+		return nil, err
+	}
+
 	return &MesosFinder{
 		balancer: balancer,
-		fetcher:  mesos.NewTaskFetcher(masters),
+		masters:  masters,
+		api:      api,
+		fetcher:  fetcher,
 	}, nil
 }
 
@@ -55,22 +74,49 @@ func (m *MesosFinder) Name() string {
 }
 
 // Balancers returns our load balancers running on Mesos
-func (m *MesosFinder) Balancers() ([]Balancer, error) {
-	tasks, err := m.fetcher.FetchTasks()
+func (m *MesosFinder) Balancers(ctx context.Context) ([]Balancer, error) {
+	groups, err := m.GroupBalancers(ctx, []string{m.balancer})
 	if err != nil {
 		return nil, err
 	}
 
-	balancers := []Balancer{}
+	return groups[m.balancer], nil
+}
+
+// Source returns identifier of Mesos cluster the finder talks to
+func (m *MesosFinder) Source() string {
+	return "mesos:" + m.api + ":" + strings.Join(m.masters, ",")
+}
+
+// GroupBalancers returns load balancers running on Mesos for
+// each of the specified balancer names, fetching tasks only once
+func (m *MesosFinder) GroupBalancers(ctx context.Context, balancers []string) (map[string][]Balancer, error) {
+	tasks, err := m.fetcher.FetchTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]Balancer, len(balancers))
+	for _, b := range balancers {
+		groups[b] = []Balancer{}
+	}
 
 	for _, task := range tasks {
-		if task.Labels["zoidberg_balancer_for"] == m.balancer {
-			balancers = append(balancers, Balancer{
-				Host: task.Host,
-				Port: task.Ports[0],
-			})
+		b := task.Labels["zoidberg_balancer_for"]
+		if _, ok := groups[b]; !ok {
+			continue
+		}
+
+		if len(task.Ports) == 0 {
+			logger.With("balancer", b, "task", task.Name).Warnf("no ports")
+			continue
 		}
+
+		groups[b] = append(groups[b], Balancer{
+			Host: task.Host,
+			Port: task.Ports[0],
+		})
 	}
 
-	return balancers, nil
+	return groups, nil
 }
@@ -0,0 +1,123 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var multiFinderFindersFlag *string
+
+func init() {
+	RegisterFinderMaker("multi", FinderMaker{
+		Flags: func() {
+			multiFinderFindersFlag = flag.String(
+				"balancer-finder-multi-finders",
+				os.Getenv("BALANCER_FINDER_MULTI_FINDERS"),
+				"balancer finders to union for multi balancer finder (finder[,finder])",
+			)
+		},
+		Maker: func(balancer string) (Finder, error) {
+			names := []string{}
+			finders := []Finder{}
+
+			for _, name := range strings.Split(*multiFinderFindersFlag, ",") {
+				name = strings.TrimSpace(name)
+				if name == "" {
+					continue
+				}
+
+				if name == "multi" {
+					return nil, errors.New("multi balancer finder can't include itself")
+				}
+
+				f, err := FinderByName(name, balancer)
+				if err != nil {
+					return nil, err
+				}
+
+				names = append(names, name)
+				finders = append(finders, f)
+			}
+
+			return NewMultiFinder(names, finders, balancer)
+		},
+	})
+}
+
+// MultiFinder represents a finder that returns balancers of several
+// finders, balancers with the same location are only returned once
+type MultiFinder struct {
+	names    []string
+	finders  []Finder
+	balancer string
+}
+
+// NewMultiFinder creates a new multi finder from named finders
+func NewMultiFinder(names []string, finders []Finder, balancer string) (*MultiFinder, error) {
+	if len(finders) == 0 {
+		return nil, errors.New("no finders specified for multi balancer finder")
+	}
+
+	if len(names) != len(finders) {
+		return nil, errors.New("expected a name for every finder of multi balancer finder")
+	}
+
+	return &MultiFinder{
+		names:    names,
+		finders:  finders,
+		balancer: balancer,
+	}, nil
+}
+
+// Name returns the name of the balancer group
+func (m *MultiFinder) Name() string {
+	return m.balancer
+}
+
+// Balancers returns balancers of all finders, the first finder
+// that returns balancer with the same location wins
+func (m *MultiFinder) Balancers(ctx context.Context) ([]Balancer, error) {
+	balancers := []Balancer{}
+	seen := map[string]bool{}
+
+	for i, f := range m.finders {
+		r, err := f.Balancers(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error finding balancers of %s: %s", m.names[i], err)
+		}
+
+		for _, b := range r {
+			if seen[b.String()] {
+				continue
+			}
+
+			seen[b.String()] = true
+			balancers = append(balancers, b)
+		}
+	}
+
+	return balancers, nil
+}
+
+// Close closes finders that hold resources, like watched files
+func (m *MultiFinder) Close() error {
+	var err error
+
+	for i, f := range m.finders {
+		c, ok := f.(io.Closer)
+		if !ok {
+			continue
+		}
+
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("error closing %s: %s", m.names[i], cerr)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,71 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMultiFinder(t *testing.T) {
+	a := NewStaticFinder([]Balancer{
+		{Host: "10.0.0.1", Port: 80},
+		{Host: "10.0.0.2", Port: 80},
+	}, "lb")
+
+	b := NewStaticFinder([]Balancer{
+		{Host: "10.0.0.2", Port: 80, Scheme: "https"},
+		{Host: "10.0.0.3", Port: 80},
+	}, "lb")
+
+	m, err := NewMultiFinder([]string{"mesos", "static"}, []Finder{a, b}, "lb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balancers, err := m.Balancers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Balancer{
+		{Host: "10.0.0.1", Port: 80},
+		{Host: "10.0.0.2", Port: 80},
+		{Host: "10.0.0.3", Port: 80},
+	}
+
+	if !reflect.DeepEqual(balancers, expected) {
+		t.Errorf("expected: %v, got: %v", expected, balancers)
+	}
+}
+
+type closingFinder struct {
+	Finder
+	closed int
+	err    error
+}
+
+func (c *closingFinder) Close() error {
+	c.closed++
+	return c.err
+}
+
+func TestMultiFinderClose(t *testing.T) {
+	a := &closingFinder{Finder: NewStaticFinder(nil, "lb"), err: errors.New("boom")}
+	b := NewStaticFinder(nil, "lb")
+	c := &closingFinder{Finder: NewStaticFinder(nil, "lb")}
+
+	m, err := NewMultiFinder([]string{"file", "static", "other"}, []Finder{a, b, c}, "lb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Close()
+	if err == nil || err.Error() != "error closing file: boom" {
+		t.Errorf("expected error of the first finder, got: %v", err)
+	}
+
+	if a.closed != 1 || c.closed != 1 {
+		t.Errorf("expected every closer to be closed once, got: %d and %d", a.closed, c.closed)
+	}
+}
@@ -1,8 +1,10 @@
 package balancer
 
 import (
+	"context"
 	"errors"
 	"flag"
+	"fmt"
 	"net"
 	"os"
 	"strconv"
@@ -17,7 +19,7 @@ func init() {
 			staticFinderBalancersFlag = flag.String(
 				"balancer-finder-static-balancers",
 				os.Getenv("BALANCER_FINDER_STATIC_BALANCERS"),
-				"list of balancers (host:port[,host:port]) for static balancer finder",
+				"list of balancers (host:port[:scheme][,host:port[:scheme]]) for static balancer finder",
 			)
 		},
 		Maker: func(balancer string) (Finder, error) {
@@ -63,14 +65,23 @@ func (s StaticFinder) Name() string {
 }
 
 // Balancers returns the static list of load balancers
-func (s StaticFinder) Balancers() ([]Balancer, error) {
+func (s StaticFinder) Balancers(ctx context.Context) ([]Balancer, error) {
 	return s.balancers, nil
 }
 
-// balanceFromString creates Balancer instance from a host:port string
+// balanceFromString creates Balancer instance from a host:port[:scheme]
+// string, scheme is either http or https
 func balancerFromString(s string) (Balancer, error) {
 	b := Balancer{}
 
+	for _, scheme := range []string{"http", "https"} {
+		if strings.HasSuffix(s, ":"+scheme) {
+			b.Scheme = scheme
+			s = strings.TrimSuffix(s, ":"+scheme)
+			break
+		}
+	}
+
 	h, p, err := net.SplitHostPort(s)
 	if err != nil {
 		return b, err
@@ -81,6 +92,10 @@ func balancerFromString(s string) (Balancer, error) {
 		return b, err
 	}
 
+	if port < 1 || port > 65535 {
+		return b, fmt.Errorf("invalid port %d", port)
+	}
+
 	b.Host = h
 	b.Port = port
 
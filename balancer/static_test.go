@@ -0,0 +1,80 @@
+package balancer
+
+import "testing"
+
+func TestBalancerFromString(t *testing.T) {
+	table := []struct {
+		s        string
+		balancer Balancer
+		str      string
+		err      bool
+	}{
+		{
+			s:        "127.0.0.1:1234",
+			balancer: Balancer{Host: "127.0.0.1", Port: 1234},
+		},
+		{
+			s:        "lb.example.com:80",
+			balancer: Balancer{Host: "lb.example.com", Port: 80},
+		},
+		{
+			s:        "[2001:db8::1]:1234",
+			balancer: Balancer{Host: "2001:db8::1", Port: 1234},
+		},
+		{
+			s:        "lb.example.com:443:https",
+			balancer: Balancer{Host: "lb.example.com", Port: 443, Scheme: "https"},
+			str:      "lb.example.com:443",
+		},
+		{
+			s:        "[2001:db8::1]:80:http",
+			balancer: Balancer{Host: "2001:db8::1", Port: 80, Scheme: "http"},
+			str:      "[2001:db8::1]:80",
+		},
+		{
+			s:   "lb.example.com:443:ftp",
+			err: true,
+		},
+		{
+			s:   "lb.example.com:0",
+			err: true,
+		},
+		{
+			s:   "2001:db8::1:1234",
+			err: true,
+		},
+		{
+			s:   "127.0.0.1",
+			err: true,
+		},
+	}
+
+	for _, row := range table {
+		b, err := balancerFromString(row.s)
+		if row.err {
+			if err == nil {
+				t.Errorf("expected error for %q, got: %v", row.s, b)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", row.s, err)
+			continue
+		}
+
+		if b != row.balancer {
+			t.Errorf("expected: %v, got: %v", row.balancer, b)
+		}
+
+		str := row.str
+		if str == "" {
+			str = row.s
+		}
+
+		if b.String() != str {
+			t.Errorf("expected string representation %q, got: %q", str, b.String())
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package zoidberg
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/bobrik/zoidberg/application"
+)
+
+// BalancerStatus is the status of state updates of a balancer
+type BalancerStatus struct {
+	Balancer     string     `json:"balancer"`
+	LastUpdate   *time.Time `json:"last_update,omitempty"`
+	FailingSince *time.Time `json:"failing_since,omitempty"`
+}
+
+// balancerStatuses returns statuses of balancers from the last discovery
+func (e *Explorer) balancerStatuses(g *group) []BalancerStatus {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	statuses := []BalancerStatus{}
+
+	d := e.discoveries[g.name]
+	if d == nil {
+		return statuses
+	}
+
+	for _, b := range d.Balancers {
+		s := BalancerStatus{Balancer: b.String()}
+
+		if u, ok := g.updated[s.Balancer]; ok {
+			t := u.time
+			s.LastUpdate = &t
+		}
+
+		if f, ok := g.failed[s.Balancer]; ok {
+			s.FailingSince = &f
+		}
+
+		statuses = append(statuses, s)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Balancer < statuses[j].Balancer
+	})
+
+	return statuses
+}
+
+// serveBalancers serves statuses of balancers of the group
+func (e *Explorer) serveBalancers(w http.ResponseWriter, req *http.Request, g *group) {
+	if req.Method != "GET" {
+		http.Error(w, "expected GET", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Add("Content-type", "application/json")
+	err := json.NewEncoder(w).Encode(e.balancerStatuses(g))
+	if err != nil {
+		logger.With("group", g.name).Warnf("error sending balancers: %s", err)
+	}
+}
+
+// serveSources serves statuses of sources of apps of the group,
+// which are only reported by finders that combine several sources
+func (e *Explorer) serveSources(w http.ResponseWriter, req *http.Request, g *group) {
+	if req.Method != "GET" {
+		http.Error(w, "expected GET", http.StatusBadRequest)
+		return
+	}
+
+	e.mutex.Lock()
+	af := g.af
+	e.mutex.Unlock()
+
+	statuses := []application.SourceStatus{}
+	if r, ok := af.(application.SourceStatusReporter); ok {
+		statuses = r.SourceStatuses()
+	}
+
+	w.Header().Add("Content-type", "application/json")
+	err := json.NewEncoder(w).Encode(statuses)
+	if err != nil {
+		logger.With("group", g.name).Warnf("error sending sources: %s", err)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configCheckInterval is how often configuration file is checked for changes
+const configCheckInterval = time.Second * 5
+
+// config represents configuration file in json or yaml, options are
+// named after cli flags without leading dash, like "application-finder"
+type config struct {
+	// Options are applied to flags unless flags are set on the command line
+	Options map[string]string `json:"options" yaml:"options"`
+	// Groups are balancer groups with options that override global ones
+	Groups map[string]map[string]string `json:"groups" yaml:"groups"`
+}
+
+// configurator applies options from configuration file on top of cli flags
+type configurator struct {
+	file     string
+	defaults map[string]string
+	explicit map[string]bool
+}
+
+// newConfigurator creates configurator for the specified file,
+// must be called after flags are parsed to remember their values
+func newConfigurator(file string) *configurator {
+	c := &configurator{
+		file:     file,
+		defaults: map[string]string{},
+		explicit: map[string]bool{},
+	}
+
+	flag.VisitAll(func(f *flag.Flag) {
+		c.defaults[f.Name] = f.Value.String()
+	})
+
+	flag.Visit(func(f *flag.Flag) {
+		c.explicit[f.Name] = true
+	})
+
+	return c
+}
+
+// load reads configuration file and applies its options to flags,
+// returning options of balancer groups defined in the file, flags
+// are left untouched if any option in the file is invalid
+func (c *configurator) load() (map[string]map[string]string, error) {
+	cfg, err := readConfig(c.file)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range c.defaults {
+		err = flag.Set(name, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for name, value := range cfg.Options {
+		if c.explicit[name] {
+			continue
+		}
+
+		err = flag.Set(name, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid option %q in %s: %s", name, c.file, err)
+		}
+	}
+
+	return cfg.Groups, nil
+}
+
+// watch calls reload on SIGHUP and when configuration file changes
+func (c *configurator) watch(reload func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	ticker := time.NewTicker(configCheckInterval)
+	defer ticker.Stop()
+
+	last := c.modTime()
+
+	for {
+		select {
+		case <-hup:
+			logger.With("file", c.file).Infof("received SIGHUP, reloading configuration")
+		case <-ticker.C:
+			m := c.modTime()
+			if m.Equal(last) {
+				continue
+			}
+
+			logger.With("file", c.file).Infof("configuration file changed, reloading")
+		}
+
+		last = c.modTime()
+		reload()
+	}
+}
+
+// modTime returns modification time of configuration file
+func (c *configurator) modTime() time.Time {
+	return modTime(c.file)
+}
+
+// modTime returns modification time of the file or zero time if it is missing
+func modTime(file string) time.Time {
+	s, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return s.ModTime()
+}
+
+// readConfig reads and validates configuration file
+func readConfig(file string) (config, error) {
+	cfg := config{}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return cfg, err
+	}
+
+	defer func() {
+		if err := f.Close(); err != nil {
+			logger.With("file", file).Warnf("error closing configuration file: %s", err)
+		}
+	}()
+
+	switch filepath.Ext(file) {
+	case ".yaml", ".yml":
+		d := yaml.NewDecoder(f)
+		d.KnownFields(true)
+		err = d.Decode(&cfg)
+	default:
+		d := json.NewDecoder(f)
+		d.DisallowUnknownFields()
+		err = d.Decode(&cfg)
+	}
+
+	if err != nil {
+		return cfg, fmt.Errorf("error decoding %s: %s", file, err)
+	}
+
+	for name, value := range cfg.Options {
+		if err := validateOption(name, value); err != nil {
+			return cfg, fmt.Errorf("%s in %s", err, file)
+		}
+	}
+
+	for group, options := range cfg.Groups {
+		for name, value := range options {
+			if err := validateOption(name, value); err != nil {
+				return cfg, fmt.Errorf("%s for group %q in %s", err, group, file)
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// validateOption checks that the flag exists and accepts the value
+// by setting it on a fresh value of the same type as the flag has
+func validateOption(name, value string) error {
+	fl := flag.Lookup(name)
+	if name == "config" || fl == nil {
+		return fmt.Errorf("unknown option %q", name)
+	}
+
+	t := reflect.TypeOf(fl.Value)
+	if t.Kind() != reflect.Ptr {
+		return nil
+	}
+
+	v, ok := reflect.New(t.Elem()).Interface().(flag.Value)
+	if !ok {
+		return nil
+	}
+
+	if err := v.Set(value); err != nil {
+		return fmt.Errorf("invalid option %q: %s", name, err)
+	}
+
+	return nil
+}
+
+// withOptions applies options to flags while running f,
+// restoring previous values of flags afterwards
+func withOptions(options map[string]string, f func() error) error {
+	previous := map[string]string{}
+
+	defer func() {
+		for name, value := range previous {
+			if err := flag.Set(name, value); err != nil {
+				logger.With("flag", name).Errorf("error restoring flag: %s", err)
+			}
+		}
+	}()
+
+	for name, value := range options {
+		fl := flag.Lookup(name)
+		if fl == nil {
+			return fmt.Errorf("unknown option %q", name)
+		}
+
+		previous[name] = fl.Value.String()
+
+		err := flag.Set(name, value)
+		if err != nil {
+			return fmt.Errorf("invalid option %q: %s", name, err)
+		}
+	}
+
+	return f()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testConfigName     = flag.String("test-config-name", "default", "")
+	testConfigInterval = flag.Duration("test-config-interval", time.Second, "")
+)
+
+func TestConfiguratorLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zoidberg-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	table := []struct {
+		file     string
+		content  string
+		name     string
+		interval time.Duration
+		groups   map[string]map[string]string
+		err      bool
+	}{
+		{
+			file:     "config.json",
+			content:  `{"options":{"test-config-name":"json","test-config-interval":"5s"},"groups":{"edge":{"test-config-name":"edge"}}}`,
+			name:     "json",
+			interval: time.Second * 5,
+			groups:   map[string]map[string]string{"edge": {"test-config-name": "edge"}},
+		},
+		{
+			file:     "config.yaml",
+			content:  "options:\n  test-config-name: yaml\n  test-config-interval: 10s\ngroups:\n  edge:\n    test-config-name: edge\n",
+			name:     "yaml",
+			interval: time.Second * 10,
+			groups:   map[string]map[string]string{"edge": {"test-config-name": "edge"}},
+		},
+		{
+			file:    "config.yml",
+			content: "options:\n  test-config-name: yml\nunknown: true\n",
+			err:     true,
+		},
+		{
+			file:    "invalid.json",
+			content: `{"options":{"test-config-name":"half","test-config-interval":"soon"}}`,
+			err:     true,
+		},
+		{
+			file:    "invalid-group.json",
+			content: `{"options":{"test-config-name":"half"},"groups":{"edge":{"test-config-interval":"soon"}}}`,
+			err:     true,
+		},
+	}
+
+	for _, row := range table {
+		*testConfigName = "default"
+		*testConfigInterval = time.Second
+
+		file := filepath.Join(dir, row.file)
+		if err := ioutil.WriteFile(file, []byte(row.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		c := newConfigurator(file)
+
+		// flags set by previous rows are not set on the command line
+		c.explicit = map[string]bool{}
+
+		// flags of testing package can't be set to their own defaults
+		for name := range c.defaults {
+			if strings.HasPrefix(name, "test.") {
+				delete(c.defaults, name)
+			}
+		}
+
+		groups, err := c.load()
+		if row.err {
+			if err == nil {
+				t.Errorf("%s: expected error, got none", row.file)
+			}
+
+			if *testConfigName != "default" || *testConfigInterval != time.Second {
+				t.Errorf("%s: flags changed by invalid file: %q, %s", row.file, *testConfigName, *testConfigInterval)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", row.file, err)
+			continue
+		}
+
+		if *testConfigName != row.name || *testConfigInterval != row.interval {
+			t.Errorf("%s: expected %q, %s, got %q, %s", row.file, row.name, row.interval, *testConfigName, *testConfigInterval)
+		}
+
+		if !reflect.DeepEqual(groups, row.groups) {
+			t.Errorf("%s: expected groups %v, got %v", row.file, row.groups, groups)
+		}
+	}
+}
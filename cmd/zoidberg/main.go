@@ -1,76 +1,449 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"path"
+	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/bobrik/zoidberg"
 	"github.com/bobrik/zoidberg/application"
+	"github.com/bobrik/zoidberg/auth"
 	"github.com/bobrik/zoidberg/balancer"
+	"github.com/bobrik/zoidberg/logging"
 	"github.com/samuel/go-zookeeper/zk"
 )
 
+var logger = logging.Component("main")
+
 func main() {
+	c := flag.String("config", os.Getenv("CONFIG"), "json or yaml configuration file, reloaded on change or SIGHUP")
 	n := flag.String("name", os.Getenv("NAME"), "zoidberg name")
 	h := flag.String("host", os.Getenv("HOST"), "host")
 	p := flag.String("port", os.Getenv("PORT"), "port")
-	b := flag.String("balancer", os.Getenv("BALANCER"), "balancer name")
+	b := flag.String("balancer", os.Getenv("BALANCER"), "balancer name or several names (name[,name])")
 	bff := flag.String("balancer-finder", os.Getenv("BALANCER_FINDER"), "balancer finder")
 	aff := flag.String("application-finder", os.Getenv("APPLICATION_FINDER"), "application finder")
 	z := flag.String("zk", os.Getenv("ZK"), "zk connection in host:port,host:port/path format")
 	i := flag.Duration("interval", time.Second, "discovery interval")
 	l := flag.Duration("laziness", time.Minute, "time to skip balancer updates if there are no changes")
+	st := flag.Duration("shutdown-timeout", time.Second*30, "time to wait for in-flight updates on shutdown")
+	hda := flag.Duration("health-discovery-age", time.Minute, "max time since the last successful discovery to be healthy, 0 disables")
+	hua := flag.Duration("health-update-age", 0, "max time since the last successful balancer update to be healthy, 0 disables")
+	hfb := flag.Int("health-failing-balancers", -1, "max number of balancers failing updates to be ready, -1 disables")
+	hzs := flag.Duration("health-zookeeper-session", 0, "max time without zookeeper session to be healthy, 0 disables")
+	ll := flag.String("log-level", envOr("LOG_LEVEL", "info"), "log level: debug, info, warn or error")
+	lf := flag.String("log-format", envOr("LOG_FORMAT", "logfmt"), "log format: logfmt or json")
+	ld := flag.Duration("log-dedup-window", time.Minute, "time to suppress repeated identical log records, 0 disables")
+	rot := flag.Duration("reaper-orphan-ttl", 0, "time since app or version was discovered to report its versions as orphaned, 0 disables")
+	rpt := flag.Duration("reaper-prune-ttl", 0, "time since app or version was discovered to remove its versions, 0 disables")
+	at := flag.String("auth-tokens", os.Getenv("AUTH_TOKENS"), "file with bearer tokens for api changes, lines are \"<token> <identity>\"")
+	ab := flag.String("auth-basic", os.Getenv("AUTH_BASIC"), "file with password hashes for api changes, lines are \"<identity>:<hash>\"")
+	atls := flag.Bool("auth-tls", false, "authenticate api changes with verified client certificates")
+	ap := flag.String("auth-permissions", os.Getenv("AUTH_PERMISSIONS"), "file with app patterns identities can change, lines are \"<identity> <pattern>[,<pattern>]\"")
+	tc := flag.String("tls-cert", os.Getenv("TLS_CERT"), "certificate file to serve api over https, reloaded on change")
+	tk := flag.String("tls-key", os.Getenv("TLS_KEY"), "private key file of the certificate, reloaded on change")
+	tca := flag.String("tls-client-ca", os.Getenv("TLS_CLIENT_CA"), "ca file to verify client certificates, reloaded on change")
+	tcr := flag.Bool("tls-client-cert-required", false, "reject clients without certificates signed by -tls-client-ca")
+	hp := flag.Bool("hash-password", false, "print hash of the password from stdin for -auth-basic and exit")
 
 	application.RegisterFlags()
 	balancer.RegisterFlags()
 
 	flag.Parse()
 
-	if *bff == "" || *aff == "" || *h == "" || *p == "" || *z == "" || *b == "" {
+	if *hp {
+		err := printPasswordHash()
+		if err != nil {
+			logger.Fatalf("%s", err)
+		}
+
+		return
+	}
+
+	var cfg *configurator
+	options := map[string]map[string]string{}
+
+	if *c != "" {
+		var err error
+
+		cfg = newConfigurator(*c)
+		options, err = cfg.load()
+		if err != nil {
+			logger.Fatalf("%s", err)
+		}
+	}
+
+	logs := logOptions{level: *ll, format: *lf, dedup: *ld}
+
+	err := setUpLogging(nil, logs)
+	if err != nil {
+		logger.Fatalf("%s", err)
+	}
+
+	if *h == "" || *p == "" || *z == "" || (len(options) == 0 && (*bff == "" || *aff == "" || *b == "")) {
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-	bf, err := balancer.FinderByName(*bff, *b)
+	zc, zch, zp, err := initZK(*z)
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatalf("%s", err)
 	}
 
-	af, err := application.FinderByName(*aff, *b)
+	groups, err := makeGroups(groupOptions(options, *b), aff, bff, zp, strings.Split(*b, ",")[0])
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatalf("%s", err)
 	}
 
-	zc, zp, err := initZK(*z)
+	e, err := zoidberg.NewExplorer(*n, groups, zc, *i, *l)
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatalf("%s", err)
 	}
 
-	e, err := zoidberg.NewExplorer(*n, af, bf, zc, zp, *i, *l)
+	// events that happen before explorer is created wait in the channel
+	go func() {
+		for ev := range zch {
+			logger.With("zk_state", ev.State, "zk_server", ev.Server).Infof("received zk event: %s", ev.Type)
+			e.ObserveZookeeperEvent(ev)
+		}
+	}()
+
+	thresholds := func() zoidberg.HealthThresholds {
+		return zoidberg.HealthThresholds{
+			DiscoveryAge:     *hda,
+			UpdateAge:        *hua,
+			FailingBalancers: *hfb,
+			ZookeeperSession: *hzs,
+		}
+	}
+
+	e.SetHealthThresholds(thresholds())
+
+	reaper := func() zoidberg.ReaperConfig {
+		c := zoidberg.ReaperConfig{OrphanTTL: *rot, PruneTTL: *rpt}
+		if c.PruneTTL > 0 && (c.OrphanTTL == 0 || c.OrphanTTL > c.PruneTTL) {
+			c.OrphanTTL = c.PruneTTL
+		}
+
+		return c
+	}
+
+	e.SetReaperConfig(reaper())
+
+	a, err := makeAuth(*at, *ab, *atls, *ap)
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatalf("%s", err)
 	}
 
+	e.SetAuth(a)
+
+	if cfg != nil {
+		host, port, zkc, cert, key, ca, required := *h, *p, *z, *tc, *tk, *tca, *tcr
+
+		go cfg.watch(func() {
+			options, err := cfg.load()
+			if err != nil {
+				logger.Errorf("error reloading configuration: %s", err)
+				return
+			}
+
+			if *h != host || *p != port || *z != zkc || *tc != cert || *tk != key || *tca != ca || *tcr != required {
+				logger.Warnf("changes of api listener, tls files and zk connection require restart")
+			}
+
+			groups, err := makeGroups(groupOptions(options, *b), aff, bff, zp, strings.Split(*b, ",")[0])
+			if err != nil {
+				logger.Errorf("error making balancer groups from configuration: %s", err)
+				return
+			}
+
+			err = e.Reconfigure(*n, groups, *i, *l)
+			if err != nil {
+				logger.Errorf("error applying configuration: %s", err)
+				return
+			}
+
+			e.SetHealthThresholds(thresholds())
+			e.SetReaperConfig(reaper())
+
+			a, err := makeAuth(*at, *ab, *atls, *ap)
+			if err != nil {
+				logger.Errorf("error applying auth configuration: %s", err)
+			} else {
+				e.SetAuth(a)
+			}
+
+			current := logOptions{level: *ll, format: *lf, dedup: *ld}
+
+			err = setUpLogging(&logs, current)
+			if err != nil {
+				logger.Errorf("error applying log configuration: %s", err)
+			} else {
+				logs = current
+			}
+
+			logger.With("file", *c).Infof("configuration reloaded")
+		})
+	}
+
+	server := &http.Server{
+		Addr:    net.JoinHostPort(strings.Trim(*h, "[]"), *p),
+		Handler: e.ServeMux(),
+	}
+
+	if *tc != "" || *tk != "" {
+		r, err := newCertReloader(*tc, *tk, *tca, *tcr)
+		if err != nil {
+			logger.Fatalf("%s", err)
+		}
+
+		server.TLSConfig = r.config()
+	}
+
+	go func() {
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+
+		if err != http.ErrServerClosed {
+			logger.Fatalf("%s", err)
+		}
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+	errs := make(chan error, 1)
 	go func() {
-		addr := fmt.Sprintf("%s:%s", *h, *p)
-		log.Fatal(http.ListenAndServe(addr, e.ServeMux()))
+		errs <- e.Run(context.Background())
 	}()
 
-	err = e.Run()
+	select {
+	case err := <-errs:
+		logger.Fatalf("%s", err)
+	case s := <-signals:
+		logger.Infof("received %s, shutting down", s)
+	}
+
+	shutdown(server, e, zc, *st)
+}
+
+// shutdown stops explorer and api server, waiting for in-flight
+// updates and requests to finish, and closes zookeeper session,
+// explorer goes first to close event streams that never finish
+func shutdown(server *http.Server, e *zoidberg.Explorer, zc *zk.Conn, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := e.Shutdown(ctx)
 	if err != nil {
-		log.Fatal(err)
+		logger.Errorf("error shutting down explorer: %s", err)
 	}
+
+	err = server.Shutdown(ctx)
+	if err != nil {
+		logger.Errorf("error shutting down api server: %s", err)
+	}
+
+	zc.Close()
+
+	logger.Infof("shutdown complete")
+}
+
+// groupOptions returns options of balancer groups from configuration
+// or balancer groups from the list of names without extra options
+func groupOptions(options map[string]map[string]string, names string) map[string]map[string]string {
+	if len(options) > 0 {
+		return options
+	}
+
+	r := map[string]map[string]string{}
+	for _, name := range strings.Split(names, ",") {
+		r[name] = map[string]string{}
+	}
+
+	return r
 }
 
-func initZK(z string) (*zk.Conn, string, error) {
+// makeGroups creates balancer groups with finders from flags overridden
+// by group options, each group keeps its state under its own name,
+// state kept at the root by older versions goes to the owner group
+// or to the first group if there is no such group
+func makeGroups(options map[string]map[string]string, aff, bff *string, zp, owner string) ([]zoidberg.Group, error) {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	if _, ok := options[owner]; !ok && len(names) > 0 {
+		owner = names[0]
+	}
+
+	groups := make([]zoidberg.Group, len(names))
+
+	for i, name := range names {
+		err := withOptions(options[name], func() error {
+			bf, err := balancer.FinderByName(*bff, name)
+			if err != nil {
+				return err
+			}
+
+			af, err := application.FinderByName(*aff, name)
+			if err != nil {
+				return err
+			}
+
+			groups[i] = zoidberg.Group{
+				Name:              name,
+				ApplicationFinder: af,
+				BalancerFinder:    bf,
+				ZookeeperPath:     path.Join(zp, name),
+			}
+
+			if name == owner {
+				groups[i].LegacyZookeeperPath = zp
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("error making balancer group %q: %s", name, err)
+		}
+	}
+
+	return groups, nil
+}
+
+// makeAuth creates authentication of api changes from files,
+// nil is returned if no authentication is configured
+func makeAuth(tokens, basic string, tls bool, permissions string) (*auth.Auth, error) {
+	authenticators := []auth.Authenticator{}
+
+	if tokens != "" {
+		a, err := auth.NewTokenAuthenticator(tokens)
+		if err != nil {
+			return nil, err
+		}
+
+		authenticators = append(authenticators, a)
+	}
+
+	if basic != "" {
+		a, err := auth.NewBasicAuthenticator(basic)
+		if err != nil {
+			return nil, err
+		}
+
+		authenticators = append(authenticators, a)
+	}
+
+	if tls {
+		authenticators = append(authenticators, auth.TLSAuthenticator{})
+	}
+
+	if len(authenticators) == 0 {
+		if permissions != "" {
+			return nil, errors.New("permissions require authentication to be configured")
+		}
+
+		return nil, nil
+	}
+
+	var p auth.Permissions
+	if permissions != "" {
+		var err error
+
+		p, err = auth.ReadPermissions(permissions)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return auth.New(authenticators, p), nil
+}
+
+// printPasswordHash prints hash of the password read from stdin
+func printPasswordHash() error {
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	hash, err := auth.HashPassword(strings.TrimRight(password, "\r\n"))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(hash)
+
+	return nil
+}
+
+// logOptions are log level, format and deduplication window
+type logOptions struct {
+	level  string
+	format string
+	dedup  time.Duration
+}
+
+// setUpLogging applies log options that differ from the previous ones,
+// so the level changed at runtime is kept while its option is the same
+func setUpLogging(previous *logOptions, o logOptions) error {
+	l, err := logging.ParseLevel(o.level)
+	if err != nil {
+		return err
+	}
+
+	f, err := logging.ParseFormat(o.format)
+	if err != nil {
+		return err
+	}
+
+	if previous == nil || previous.level != o.level {
+		logging.SetLevel(l)
+	}
+
+	if previous == nil || previous.format != o.format {
+		logging.SetFormat(f)
+	}
+
+	if previous == nil || previous.dedup != o.dedup {
+		logging.SetDedupWindow(o.dedup)
+	}
+
+	return nil
+}
+
+// envOr returns value of environment variable or default value if it is empty
+func envOr(name, value string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+
+	return value
+}
+
+func initZK(z string) (*zk.Conn, <-chan zk.Event, string, error) {
 	if !strings.Contains(z, "/") {
-		return nil, "", errors.New("zk connection string is invalid")
+		return nil, nil, "", errors.New("zk connection string is invalid")
 	}
 
 	zz := strings.SplitN(z, "/", 2)
@@ -79,14 +452,8 @@ func initZK(z string) (*zk.Conn, string, error) {
 
 	zc, zch, err := zk.Connect(strings.Split(zh, ","), time.Minute)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, "", err
 	}
 
-	go func() {
-		for e := range zch {
-			log.Println("received zk event:", e)
-		}
-	}()
-
-	return zc, zp, nil
+	return zc, zch, zp, nil
 }
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bobrik/zoidberg/logging"
+)
+
+func TestSetUpLoggingKeepsRuntimeLevel(t *testing.T) {
+	defer logging.SetLevel(logging.GetLevel())
+	defer logging.SetFormat(logging.Logfmt)
+
+	logs := logOptions{level: "info", format: "logfmt", dedup: time.Minute}
+
+	if err := setUpLogging(nil, logs); err != nil {
+		t.Fatal(err)
+	}
+
+	// level is changed at runtime with /log/level
+	logging.SetLevel(logging.Debug)
+
+	reloaded := logOptions{level: "info", format: "json", dedup: time.Minute}
+
+	if err := setUpLogging(&logs, reloaded); err != nil {
+		t.Fatal(err)
+	}
+
+	if logging.GetLevel() != logging.Debug {
+		t.Errorf("expected runtime level to be kept, got %s", logging.GetLevel())
+	}
+
+	changed := logOptions{level: "warn", format: "json", dedup: time.Minute}
+
+	if err := setUpLogging(&reloaded, changed); err != nil {
+		t.Fatal(err)
+	}
+
+	if logging.GetLevel() != logging.Warn {
+		t.Errorf("expected changed level to be applied, got %s", logging.GetLevel())
+	}
+
+	if err := setUpLogging(&changed, logOptions{level: "loud", format: "json"}); err == nil {
+		t.Errorf("expected error for invalid level")
+	}
+}
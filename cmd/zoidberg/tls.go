@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// tlsCheckInterval is how often certificate files are checked for changes
+const tlsCheckInterval = time.Second * 5
+
+// certReloader serves certificate and client ca from disk,
+// reloading them when files change without a restart
+type certReloader struct {
+	cert     string
+	key      string
+	ca       string
+	required bool
+
+	certificate *tls.Certificate
+	pool        *x509.CertPool
+	modified    time.Time
+	checked     time.Time
+	mutex       sync.Mutex
+}
+
+// newCertReloader loads certificate, key and optional client ca,
+// clients must present certificates signed by the ca if required
+func newCertReloader(cert, key, ca string, required bool) (*certReloader, error) {
+	if cert == "" || key == "" {
+		return nil, errors.New("both certificate and key are required for tls")
+	}
+
+	if required && ca == "" {
+		return nil, errors.New("client ca is required to verify client certificates")
+	}
+
+	r := &certReloader{
+		cert:     cert,
+		key:      key,
+		ca:       ca,
+		required: required,
+	}
+
+	err := r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// load reads certificate, key and client ca from disk
+func (r *certReloader) load() error {
+	modified := r.modTime()
+
+	certificate, err := tls.LoadX509KeyPair(r.cert, r.key)
+	if err != nil {
+		return fmt.Errorf("error loading certificate: %s", err)
+	}
+
+	var pool *x509.CertPool
+	if r.ca != "" {
+		b, err := ioutil.ReadFile(r.ca)
+		if err != nil {
+			return fmt.Errorf("error loading client ca: %s", err)
+		}
+
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return fmt.Errorf("no certificates found in client ca %s", r.ca)
+		}
+	}
+
+	r.mutex.Lock()
+	r.certificate = &certificate
+	r.pool = pool
+	r.modified = modified
+	r.mutex.Unlock()
+
+	return nil
+}
+
+// reloadIfChanged reloads files if they changed since the last check,
+// previous certificate is kept if new files can't be loaded
+func (r *certReloader) reloadIfChanged() {
+	r.mutex.Lock()
+	if time.Since(r.checked) < tlsCheckInterval {
+		r.mutex.Unlock()
+		return
+	}
+
+	r.checked = time.Now()
+	modified := r.modified
+	r.mutex.Unlock()
+
+	if r.modTime().Equal(modified) {
+		return
+	}
+
+	err := r.load()
+	if err != nil {
+		logger.With("cert", r.cert).Errorf("error reloading tls files, keeping previous ones: %s", err)
+		return
+	}
+
+	logger.With("cert", r.cert).Infof("tls files reloaded")
+}
+
+// modTime returns the latest modification time of files
+func (r *certReloader) modTime() time.Time {
+	latest := time.Time{}
+
+	for _, file := range []string{r.cert, r.key, r.ca} {
+		if file == "" {
+			continue
+		}
+
+		if m := modTime(file); m.After(latest) {
+			latest = m
+		}
+	}
+
+	return latest
+}
+
+// config returns tls configuration that uses the current files
+func (r *certReloader) config() *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.reloadIfChanged()
+
+			r.mutex.Lock()
+			defer r.mutex.Unlock()
+
+			c := &tls.Config{
+				Certificates: []tls.Certificate{*r.certificate},
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   []string{"http/1.1"},
+			}
+
+			if r.pool != nil {
+				c.ClientCAs = r.pool
+				c.ClientAuth = tls.VerifyClientCertIfGiven
+				if r.required {
+					c.ClientAuth = tls.RequireAndVerifyClientCert
+				}
+			}
+
+			return c, nil
+		},
+	}
+}
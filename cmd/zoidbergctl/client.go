@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// clientTimeout is the timeout of api requests except streaming ones
+const clientTimeout = time.Second * 10
+
+// target is where and how to reach zoidberg, it is read from
+// configuration file and environment, flags override both
+type target struct {
+	URL      string `json:"url"`
+	Group    string `json:"group"`
+	Token    string `json:"token"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	CAFile   string `json:"ca_file"`
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+}
+
+// defaultConfigFile returns path of configuration file in home directory
+func defaultConfigFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".zoidbergctl.json")
+}
+
+// readTarget reads target from configuration file, missing default
+// file is not an error, then applies environment variables
+func readTarget(file string, explicit bool) (target, error) {
+	t := target{}
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		if explicit || !os.IsNotExist(err) {
+			return t, err
+		}
+	} else {
+		err = json.Unmarshal(b, &t)
+		if err != nil {
+			return t, fmt.Errorf("error decoding %s: %s", file, err)
+		}
+	}
+
+	for env, value := range map[string]*string{
+		"ZOIDBERG_URL":      &t.URL,
+		"ZOIDBERG_GROUP":    &t.Group,
+		"ZOIDBERG_TOKEN":    &t.Token,
+		"ZOIDBERG_USERNAME": &t.Username,
+		"ZOIDBERG_PASSWORD": &t.Password,
+		"ZOIDBERG_CA_FILE":  &t.CAFile,
+	} {
+		if v := os.Getenv(env); v != "" {
+			*value = v
+		}
+	}
+
+	return t, nil
+}
+
+// client talks to zoidberg api
+type client struct {
+	target target
+	http   *http.Client
+	stream *http.Client
+}
+
+// newClient creates a client for the target
+func newClient(t target) (*client, error) {
+	if t.URL == "" {
+		return nil, fmt.Errorf("zoidberg url is not set, use -url, ZOIDBERG_URL or url in %s", defaultConfigFile())
+	}
+
+	if !strings.Contains(t.URL, "://") {
+		t.URL = "http://" + t.URL
+	}
+
+	t.URL = strings.TrimRight(t.URL, "/")
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+	}
+
+	if t.CAFile != "" || t.CertFile != "" {
+		c := &tls.Config{}
+
+		if t.CAFile != "" {
+			b, err := ioutil.ReadFile(t.CAFile)
+			if err != nil {
+				return nil, err
+			}
+
+			c.RootCAs = x509.NewCertPool()
+			if !c.RootCAs.AppendCertsFromPEM(b) {
+				return nil, fmt.Errorf("no certificates found in %s", t.CAFile)
+			}
+		}
+
+		if t.CertFile != "" {
+			cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+			if err != nil {
+				return nil, err
+			}
+
+			c.Certificates = []tls.Certificate{cert}
+		}
+
+		transport.TLSClientConfig = c
+	}
+
+	return &client{
+		target: t,
+		http:   &http.Client{Transport: transport, Timeout: clientTimeout},
+		stream: &http.Client{Transport: transport},
+	}, nil
+}
+
+// url returns url of the api path in the target group
+func (c *client) url(p string) string {
+	if c.target.Group != "" {
+		return c.target.URL + "/groups/" + c.target.Group + p
+	}
+
+	return c.target.URL + p
+}
+
+// request makes an authenticated request to the url
+func (c *client) request(method, u string, body interface{}) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.target.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.target.Token)
+	} else if c.target.Username != "" {
+		req.SetBasicAuth(c.target.Username, c.target.Password)
+	}
+
+	return req, nil
+}
+
+// do makes an api request and decodes response into result if it is set
+func (c *client) do(method, p string, body, result interface{}) error {
+	req, err := c.request(method, c.url(p), body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	err = checkResponse(resp)
+	if err != nil {
+		return fmt.Errorf("%s %s: %s", method, p, err)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// checkResponse returns error with response body for unsuccessful responses
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	b, _ := ioutil.ReadAll(resp.Body)
+
+	return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(b)))
+}
@@ -0,0 +1,593 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bobrik/zoidberg"
+	"github.com/bobrik/zoidberg/state"
+)
+
+// shiftScale is the weight a version gets per server for all traffic when
+// it has the most servers, it limits precision of shares set by shift
+const shiftScale = 1000
+
+// ctl runs subcommands against zoidberg
+type ctl struct {
+	client *client
+	json   bool
+	out    io.Writer
+}
+
+// print prints value as json or as a table with header and rows
+func (c *ctl) print(v interface{}, header []string, rows [][]string) error {
+	if c.json {
+		e := json.NewEncoder(c.out)
+		e.SetIndent("", "  ")
+		return e.Encode(v)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	return w.Flush()
+}
+
+// apps lists discovered apps with their servers per version
+func (c *ctl) apps(args []string) error {
+	d := zoidberg.DiscoveryResult{}
+	err := c.client.do("GET", "/discovery", nil, &d)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(d.Apps))
+	for name := range d.Apps {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	rows := [][]string{}
+	for _, name := range names {
+		counts := serverCounts(d.Apps[name].Servers)
+
+		versions := []string{}
+		for _, v := range sortedCounts(counts) {
+			versions = append(versions, fmt.Sprintf("%s:%d", v, counts[v]))
+		}
+
+		rows = append(rows, []string{name, strconv.Itoa(len(d.Apps[name].Servers)), strings.Join(versions, " ")})
+	}
+
+	return c.print(d.Apps, []string{"APP", "SERVERS", "VERSIONS"}, rows)
+}
+
+// servers lists servers of the app
+func (c *ctl) servers(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: servers <app>")
+	}
+
+	a, err := c.app(args[0])
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{}
+	for _, s := range a.Servers {
+		ports := []string{}
+		for _, p := range s.Ports {
+			ports = append(ports, strconv.Itoa(p))
+		}
+
+		rows = append(rows, []string{net.JoinHostPort(s.Host, strconv.Itoa(s.Port)), strings.Join(ports, ","), s.Version})
+	}
+
+	return c.print(a.Servers, []string{"SERVER", "PORTS", "VERSION"}, rows)
+}
+
+// app returns discovered app with versions and effective weights
+func (c *ctl) app(name string) (zoidberg.AppDiscovery, error) {
+	a := zoidberg.AppDiscovery{}
+	err := c.client.do("GET", "/discovery/"+name, nil, &a)
+	return a, err
+}
+
+// versions gets, sets or shifts versions of the app
+func (c *ctl) versions(args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: versions get|set|shift <app> [args]")
+	}
+
+	switch args[0] {
+	case "get":
+		return c.versionsGet(args[1])
+	case "set":
+		return c.versionsSet(args[1], args[2:])
+	case "shift":
+		return c.versionsShift(args[1], args[2:])
+	default:
+		return fmt.Errorf("unknown versions command %q", args[0])
+	}
+}
+
+// versionsGet prints versions of the app with effective traffic shares
+func (c *ctl) versionsGet(name string) error {
+	a, err := c.app(name)
+	if err != nil {
+		return err
+	}
+
+	counts := serverCounts(a.Servers)
+
+	all := map[string]bool{}
+	for v := range counts {
+		all[v] = true
+	}
+
+	for v := range a.Versions {
+		all[v] = true
+	}
+
+	rows := [][]string{}
+	for _, v := range sortedSet(all) {
+		weight := "-"
+		if version, ok := a.Versions[v]; ok {
+			weight = strconv.Itoa(version.Weight)
+		}
+
+		rows = append(rows, []string{v, strconv.Itoa(counts[v]), weight, fmt.Sprintf("%.1f%%", a.Weights[v]*100)})
+	}
+
+	return c.print(map[string]interface{}{"versions": a.Versions, "weights": a.Weights}, []string{"VERSION", "SERVERS", "WEIGHT", "SHARE"}, rows)
+}
+
+// versionsSet sets versions of the app from version=weight arguments
+func (c *ctl) versionsSet(name string, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: versions set <app> <version>=<weight> [<version>=<weight>]")
+	}
+
+	versions := state.Versions{}
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("expected <version>=<weight>, got %q", arg)
+		}
+
+		weight, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return fmt.Errorf("invalid weight of version %q: %s", parts[0], err)
+		}
+
+		versions[parts[0]] = state.Version{Weight: weight}
+	}
+
+	err := c.client.do("PUT", "/versions/"+name, versions, nil)
+	if err != nil {
+		return err
+	}
+
+	return c.versionsGet(name)
+}
+
+// versionsShift sends the percentage of traffic to the version,
+// other versions keep their proportions of the rest of traffic
+func (c *ctl) versionsShift(name string, args []string) error {
+	fs := flag.NewFlagSet("versions shift", flag.ContinueOnError)
+	to := fs.String("to", "", "version to shift traffic to")
+	percent := fs.Float64("percent", 100, "percentage of traffic the version should get")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *to == "" {
+		return errors.New("usage: versions shift <app> --to <version> --percent <percent>")
+	}
+
+	a, err := c.app(name)
+	if err != nil {
+		return err
+	}
+
+	versions, err := shiftWeights(a.Weights, serverCounts(a.Servers), *to, *percent)
+	if err != nil {
+		return err
+	}
+
+	err = c.client.do("PUT", "/versions/"+name, versions, nil)
+	if err != nil {
+		return err
+	}
+
+	return c.versionsGet(name)
+}
+
+// shiftWeights returns weights that give the percentage of traffic
+// to the version, other versions with servers share the rest of
+// traffic in current proportions or evenly if they get nothing now,
+// weight is assigned to every server, so shares are divided by counts
+func shiftWeights(shares map[string]float64, counts map[string]int, to string, percent float64) (state.Versions, error) {
+	if percent < 0 || percent > 100 {
+		return nil, fmt.Errorf("percent must be between 0 and 100, got %v", percent)
+	}
+
+	if _, ok := shares[to]; !ok {
+		return nil, fmt.Errorf("version %q has no servers", to)
+	}
+
+	p := percent / 100
+
+	others := []string{}
+	total := 0.0
+	for v, share := range shares {
+		if v == to {
+			continue
+		}
+
+		others = append(others, v)
+		total += share
+	}
+
+	if len(others) == 0 && p < 1 {
+		return nil, fmt.Errorf("no other versions to send %.1f%% of traffic to", (1-p)*100)
+	}
+
+	most := 1
+	for _, n := range counts {
+		if n > most {
+			most = n
+		}
+	}
+
+	weight := func(v string, share float64) state.Version {
+		n := counts[v]
+		if n < 1 {
+			n = 1
+		}
+
+		return state.Version{Weight: int(math.Round(share * float64(shiftScale*most) / float64(n)))}
+	}
+
+	versions := state.Versions{
+		to: weight(to, p),
+	}
+
+	for _, v := range others {
+		share := (1 - p) / float64(len(others))
+		if total > 0 {
+			share = shares[v] / total * (1 - p)
+		}
+
+		versions[v] = weight(v, share)
+	}
+
+	return versions, nil
+}
+
+// balancers lists discovered balancers
+func (c *ctl) balancers(args []string) error {
+	d := zoidberg.DiscoveryResult{}
+	err := c.client.do("GET", "/discovery", nil, &d)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{}
+	for _, b := range d.Balancers {
+		rows = append(rows, []string{b.String()})
+	}
+
+	return c.print(d.Balancers, []string{"BALANCER"}, rows)
+}
+
+// state prints versions of all apps or compares them with a file
+func (c *ctl) state(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: state get|diff <file>")
+	}
+
+	current := map[string]state.Versions{}
+	err := c.client.do("GET", "/versions", nil, &current)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "get":
+		rows := [][]string{}
+		for _, app := range sortedApps(current) {
+			for _, v := range sortedVersions(current[app]) {
+				rows = append(rows, []string{app, v, strconv.Itoa(current[app][v].Weight)})
+			}
+		}
+
+		return c.print(current, []string{"APP", "VERSION", "WEIGHT"}, rows)
+	case "diff":
+		if len(args) != 2 {
+			return errors.New("usage: state diff <file>")
+		}
+
+		desired, err := readVersions(args[1])
+		if err != nil {
+			return err
+		}
+
+		changes := diffVersions(current, desired)
+
+		rows := [][]string{}
+		for _, ch := range changes {
+			rows = append(rows, []string{ch.App, ch.Version, weightString(ch.Current), weightString(ch.Desired)})
+		}
+
+		err = c.print(changes, []string{"APP", "VERSION", "CURRENT", "DESIRED"}, rows)
+		if err != nil {
+			return err
+		}
+
+		if len(changes) > 0 {
+			return errDiffers
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unknown state command %q", args[0])
+	}
+}
+
+// errDiffers is returned when state differs from the desired one
+var errDiffers = errors.New("state differs")
+
+// change is a difference in weight of a version, nil weight
+// means that the version is not set
+type change struct {
+	App     string `json:"app"`
+	Version string `json:"version"`
+	Current *int   `json:"current"`
+	Desired *int   `json:"desired"`
+}
+
+// diffVersions returns changes needed to get from current to desired versions
+func diffVersions(current, desired map[string]state.Versions) []change {
+	apps := map[string]bool{}
+	for app := range current {
+		apps[app] = true
+	}
+
+	for app := range desired {
+		apps[app] = true
+	}
+
+	changes := []change{}
+	for _, app := range sortedSet(apps) {
+		versions := map[string]bool{}
+		for v := range current[app] {
+			versions[v] = true
+		}
+
+		for v := range desired[app] {
+			versions[v] = true
+		}
+
+		for _, v := range sortedSet(versions) {
+			ch := change{App: app, Version: v}
+
+			if version, ok := current[app][v]; ok {
+				ch.Current = &version.Weight
+			}
+
+			if version, ok := desired[app][v]; ok {
+				ch.Desired = &version.Weight
+			}
+
+			if ch.Current != nil && ch.Desired != nil && *ch.Current == *ch.Desired {
+				continue
+			}
+
+			changes = append(changes, ch)
+		}
+	}
+
+	return changes
+}
+
+// readVersions reads versions of apps from the file, both outputs
+// of "state get" and full state with "versions" key are accepted
+func readVersions(file string) (map[string]state.Versions, error) {
+	var b []byte
+	var err error
+
+	if file == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(file)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	s := state.State{}
+	err = json.Unmarshal(b, &s)
+	if err == nil && s.Versions != nil {
+		return s.Versions, nil
+	}
+
+	versions := map[string]state.Versions{}
+	err = json.Unmarshal(b, &versions)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding %s: %s", file, err)
+	}
+
+	return versions, nil
+}
+
+// weightString formats optional weight
+func weightString(w *int) string {
+	if w == nil {
+		return "-"
+	}
+
+	return strconv.Itoa(*w)
+}
+
+// watch prints events as they happen, reconnecting on errors
+func (c *ctl) watch(args []string) error {
+	last := ""
+
+	for {
+		err := c.watchOnce(&last)
+		if err != nil {
+			// the same event would be received again after reconnecting
+			if _, ok := err.(invalidEventError); ok {
+				return err
+			}
+
+			fmt.Fprintf(os.Stderr, "error watching events: %s, reconnecting\n", err)
+		}
+
+		time.Sleep(time.Second)
+	}
+}
+
+// watchOnce prints events from a single event stream,
+// last is the id of the last received event
+func (c *ctl) watchOnce(last *string) error {
+	// events of all groups are served at the root
+	u := c.client.target.URL + "/events"
+	if c.client.target.Group != "" {
+		u += "?group=" + url.QueryEscape(c.client.target.Group)
+	}
+
+	req, err := c.client.request("GET", u, nil)
+	if err != nil {
+		return err
+	}
+
+	if *last != "" {
+		req.Header.Set("Last-Event-ID", *last)
+	}
+
+	resp, err := c.client.stream.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	err = checkResponse(resp)
+	if err != nil {
+		return err
+	}
+
+	return c.readEvents(resp, last)
+}
+
+// maxEventSize is the largest event that can be read, events
+// with servers of large apps are much larger than lines of text
+const maxEventSize = 16 << 20
+
+// invalidEventError is an event that can't be read
+type invalidEventError struct {
+	err error
+}
+
+// Error returns the reason why the event can't be read
+func (e invalidEventError) Error() string {
+	return e.err.Error()
+}
+
+// readEvents prints events from server-sent events stream
+func (c *ctl) readEvents(resp *http.Response, last *string) error {
+	s := bufio.NewScanner(resp.Body)
+	s.Buffer(make([]byte, 64*1024), maxEventSize)
+
+	for s.Scan() {
+		line := s.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		e := zoidberg.Event{}
+		err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e)
+		if err != nil {
+			return invalidEventError{fmt.Errorf("error decoding event after %q: %s", *last, err)}
+		}
+
+		*last = strconv.FormatUint(e.ID, 10)
+
+		if c.json {
+			err = json.NewEncoder(c.out).Encode(e)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		fmt.Fprintf(c.out, "%s  %-16s  %-10s  %s\n", e.Time.Format(time.RFC3339), e.Type, e.Group, eventDetails(e))
+	}
+
+	if s.Err() == bufio.ErrTooLong {
+		return invalidEventError{fmt.Errorf("event after %q is larger than %d bytes", *last, maxEventSize)}
+	}
+
+	if s.Err() != nil {
+		return s.Err()
+	}
+
+	return io.ErrUnexpectedEOF
+}
+
+// eventDetails returns human readable details of the event
+func eventDetails(e zoidberg.Event) string {
+	details := []string{}
+
+	if e.App != "" {
+		details = append(details, "app="+e.App)
+	}
+
+	if e.Balancer != "" {
+		details = append(details, "balancer="+e.Balancer)
+	}
+
+	if e.Type == zoidberg.EventServersChanged {
+		details = append(details, fmt.Sprintf("servers=%d", len(e.Servers)))
+	}
+
+	for _, v := range sortedVersions(e.Versions) {
+		details = append(details, fmt.Sprintf("%s:%d", v, e.Versions[v].Weight))
+	}
+
+	if e.Identity != "" {
+		details = append(details, "identity="+e.Identity)
+	}
+
+	if e.Error != "" {
+		details = append(details, "error="+e.Error)
+	}
+
+	return strings.Join(details, " ")
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bobrik/zoidberg/state"
+)
+
+func TestShiftWeights(t *testing.T) {
+	table := []struct {
+		shares   map[string]float64
+		counts   map[string]int
+		to       string
+		percent  float64
+		versions state.Versions
+		valid    bool
+	}{
+		{
+			shares:   map[string]float64{"1": 1, "2": 0},
+			counts:   map[string]int{"1": 3, "2": 1},
+			to:       "2",
+			percent:  20,
+			versions: state.Versions{"1": {Weight: 800}, "2": {Weight: 600}},
+			valid:    true,
+		},
+		{
+			shares:   map[string]float64{"1": 0.5, "2": 0.25, "3": 0.25},
+			counts:   map[string]int{"1": 1, "2": 1, "3": 1},
+			to:       "3",
+			percent:  50,
+			versions: state.Versions{"1": {Weight: 333}, "2": {Weight: 167}, "3": {Weight: 500}},
+			valid:    true,
+		},
+		{
+			shares:   map[string]float64{"1": 0.6, "2": 0.4, "3": 0},
+			counts:   map[string]int{"1": 3, "2": 2, "3": 1},
+			to:       "3",
+			percent:  10,
+			versions: state.Versions{"1": {Weight: 540}, "2": {Weight: 540}, "3": {Weight: 300}},
+			valid:    true,
+		},
+		{
+			shares:   map[string]float64{"1": 0, "2": 0, "3": 0},
+			counts:   map[string]int{"1": 2, "2": 2, "3": 1},
+			to:       "3",
+			percent:  50,
+			versions: state.Versions{"1": {Weight: 250}, "2": {Weight: 250}, "3": {Weight: 1000}},
+			valid:    true,
+		},
+		{
+			shares:   map[string]float64{"1": 0.5, "2": 0.5},
+			counts:   map[string]int{"1": 1, "2": 1},
+			to:       "2",
+			percent:  100,
+			versions: state.Versions{"1": {Weight: 0}, "2": {Weight: 1000}},
+			valid:    true,
+		},
+		{
+			shares:  map[string]float64{"1": 1},
+			to:      "2",
+			percent: 20,
+			valid:   false,
+		},
+		{
+			shares:  map[string]float64{"1": 1},
+			to:      "1",
+			percent: 20,
+			valid:   false,
+		},
+		{
+			shares:  map[string]float64{"1": 1, "2": 0},
+			to:      "2",
+			percent: 120,
+			valid:   false,
+		},
+	}
+
+	for i, row := range table {
+		versions, err := shiftWeights(row.shares, row.counts, row.to, row.percent)
+		if (err == nil) != row.valid {
+			t.Errorf("row %d: expected valid: %v, got: %v", i, row.valid, err)
+			continue
+		}
+
+		if row.valid && !reflect.DeepEqual(versions, row.versions) {
+			t.Errorf("row %d: expected: %v, got: %v", i, row.versions, versions)
+		}
+	}
+}
+
+func TestDiffVersions(t *testing.T) {
+	one, two := 1, 2
+
+	current := map[string]state.Versions{
+		"same":    {"1": {Weight: 1}},
+		"changed": {"1": {Weight: 1}, "2": {Weight: 1}},
+		"gone":    {"1": {Weight: 1}},
+	}
+
+	desired := map[string]state.Versions{
+		"same":    {"1": {Weight: 1}},
+		"changed": {"1": {Weight: 2}, "2": {Weight: 1}},
+		"new":     {"1": {Weight: 1}},
+	}
+
+	expected := []change{
+		{App: "changed", Version: "1", Current: &one, Desired: &two},
+		{App: "gone", Version: "1", Current: &one},
+		{App: "new", Version: "1", Desired: &one},
+	}
+
+	changes := diffVersions(current, desired)
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected: %+v, got: %+v", expected, changes)
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	large := `{"id":2,"type":"servers_changed","group":"lb","app":"` + strings.Repeat("a", 100*1024) + `"}`
+
+	table := []struct {
+		body    string
+		last    string
+		invalid bool
+	}{
+		{
+			body: "id: 1\nevent: versions_changed\ndata: {\"id\":1,\"type\":\"versions_changed\"}\n\n",
+			last: "1",
+		},
+		{
+			body: "id: 2\nevent: servers_changed\ndata: " + large + "\n\n",
+			last: "2",
+		},
+		{
+			body:    "id: 1\ndata: {\"id\":1}\n\nid: 2\ndata: {\"id\":\n\n",
+			last:    "1",
+			invalid: true,
+		},
+		{
+			body:    "id: 1\ndata: {\"id\":1}\n\nid: 2\ndata: " + strings.Repeat("a", maxEventSize+1) + "\n\n",
+			last:    "1",
+			invalid: true,
+		},
+	}
+
+	for i, row := range table {
+		c := &ctl{out: ioutil.Discard}
+		last := ""
+
+		err := c.readEvents(&http.Response{Body: ioutil.NopCloser(strings.NewReader(row.body))}, &last)
+
+		_, invalid := err.(invalidEventError)
+		if invalid != row.invalid {
+			t.Errorf("row %d: expected invalid event: %v, got: %v", i, row.invalid, err)
+		}
+
+		if last != row.last {
+			t.Errorf("row %d: expected last event id %q, got %q", i, row.last, last)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/bobrik/zoidberg/application"
+	"github.com/bobrik/zoidberg/state"
+)
+
+const usage = `Usage: zoidbergctl [flags] <command> [args]
+
+Commands:
+  apps                                          list apps with servers per version
+  servers <app>                                 list servers of the app
+  versions get <app>                            show versions with effective traffic shares
+  versions set <app> <version>=<weight> [...]   set versions of the app
+  versions shift <app> --to <version> --percent <percent>
+                                                send percentage of traffic to the version
+  balancers                                     list balancers
+  state get                                     show versions of all apps
+  state diff <file>                             compare versions of all apps with the file,
+                                                exits with 1 if there are differences
+  watch                                         print events as they happen
+
+Target is read from %s or -config file,
+then from ZOIDBERG_URL, ZOIDBERG_GROUP, ZOIDBERG_TOKEN, ZOIDBERG_USERNAME,
+ZOIDBERG_PASSWORD and ZOIDBERG_CA_FILE environment variables, flags override both.
+
+Flags:
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, usage, defaultConfigFile())
+		flag.PrintDefaults()
+	}
+
+	c := flag.String("config", "", "configuration file with target")
+	u := flag.String("url", "", "zoidberg url, for example http://zoidberg:12345")
+	g := flag.String("group", "", "balancer group")
+	t := flag.String("token", "", "bearer token")
+	o := flag.String("o", "table", "output format: table or json")
+
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *o != "table" && *o != "json" {
+		fail(fmt.Errorf("unknown output format %q", *o))
+	}
+
+	file := *c
+	if file == "" {
+		file = defaultConfigFile()
+	}
+
+	target, err := readTarget(file, *c != "")
+	if err != nil {
+		fail(err)
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "url":
+			target.URL = *u
+		case "group":
+			target.Group = *g
+		case "token":
+			target.Token = *t
+		}
+	})
+
+	cl, err := newClient(target)
+	if err != nil {
+		fail(err)
+	}
+
+	ctl := &ctl{
+		client: cl,
+		json:   *o == "json",
+		out:    os.Stdout,
+	}
+
+	commands := map[string]func([]string) error{
+		"apps":      ctl.apps,
+		"servers":   ctl.servers,
+		"versions":  ctl.versions,
+		"balancers": ctl.balancers,
+		"state":     ctl.state,
+		"watch":     ctl.watch,
+	}
+
+	command, ok := commands[flag.Arg(0)]
+	if !ok {
+		fail(fmt.Errorf("unknown command %q", flag.Arg(0)))
+	}
+
+	err = command(flag.Args()[1:])
+	if err == errDiffers {
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fail(err)
+	}
+}
+
+// fail prints the error and exits
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "zoidbergctl: %s\n", err)
+	os.Exit(2)
+}
+
+// serverCounts returns numbers of servers per version
+func serverCounts(servers []application.Server) map[string]int {
+	r := map[string]int{}
+	for _, s := range servers {
+		r[s.Version]++
+	}
+
+	return r
+}
+
+// sortedSet returns sorted keys of the set
+func sortedSet(m map[string]bool) []string {
+	r := make([]string, 0, len(m))
+	for k := range m {
+		r = append(r, k)
+	}
+
+	sort.Strings(r)
+
+	return r
+}
+
+// sortedCounts returns sorted keys of the counts
+func sortedCounts(m map[string]int) []string {
+	r := make([]string, 0, len(m))
+	for k := range m {
+		r = append(r, k)
+	}
+
+	sort.Strings(r)
+
+	return r
+}
+
+// sortedVersions returns sorted names of the versions
+func sortedVersions(v state.Versions) []string {
+	r := make([]string, 0, len(v))
+	for k := range v {
+		r = append(r, k)
+	}
+
+	sort.Strings(r)
+
+	return r
+}
+
+// sortedApps returns sorted names of apps with versions
+func sortedApps(m map[string]state.Versions) []string {
+	r := make([]string, 0, len(m))
+	for k := range m {
+		r = append(r, k)
+	}
+
+	sort.Strings(r)
+
+	return r
+}
@@ -0,0 +1,144 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bobrik/zoidberg/logging"
+)
+
+var logger = logging.Component("consul")
+
+// Client talks to consul http api
+type Client struct {
+	address    string
+	datacenter string
+	token      string
+	client     *http.Client
+}
+
+// ServiceEntry is an instance of a service with its node
+type ServiceEntry struct {
+	Node    Node    `json:"Node"`
+	Service Service `json:"Service"`
+}
+
+// Node is a consul node
+type Node struct {
+	Node    string `json:"Node"`
+	Address string `json:"Address"`
+}
+
+// Service is a service registered on a node
+type Service struct {
+	ID      string            `json:"ID"`
+	Service string            `json:"Service"`
+	Tags    []string          `json:"Tags"`
+	Address string            `json:"Address"`
+	Port    int               `json:"Port"`
+	Meta    map[string]string `json:"Meta"`
+}
+
+// NewClient creates a new client for consul at the address,
+// datacenter and token are optional
+func NewClient(address, datacenter, token string) *Client {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+
+	return &Client{
+		address:    strings.TrimRight(address, "/"),
+		datacenter: datacenter,
+		token:      token,
+		client:     &http.Client{},
+	}
+}
+
+// Services returns names of services with their tags, blocking
+// for up to wait until consul index changes from the specified one
+func (c *Client) Services(ctx context.Context, index uint64, wait time.Duration) (map[string][]string, uint64, error) {
+	services := map[string][]string{}
+	index, err := c.get(ctx, "/v1/catalog/services", url.Values{}, index, wait, &services)
+	return services, index, err
+}
+
+// PassingEntries returns instances of the service that pass health
+// checks, blocking for up to wait until consul index changes
+func (c *Client) PassingEntries(ctx context.Context, service string, index uint64, wait time.Duration) ([]ServiceEntry, uint64, error) {
+	entries := []ServiceEntry{}
+	v := url.Values{"passing": []string{"true"}}
+	index, err := c.get(ctx, "/v1/health/service/"+url.PathEscape(service), v, index, wait, &entries)
+	return entries, index, err
+}
+
+// get makes a blocking query and decodes the response, returning new index
+func (c *Client) get(ctx context.Context, p string, v url.Values, index uint64, wait time.Duration, result interface{}) (uint64, error) {
+	if c.datacenter != "" {
+		v.Set("dc", c.datacenter)
+	}
+
+	if index > 0 {
+		v.Set("index", strconv.FormatUint(index, 10))
+		v.Set("wait", fmt.Sprintf("%dms", wait/time.Millisecond))
+	}
+
+	req, err := http.NewRequest("GET", c.address+p+"?"+v.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	req = req.WithContext(ctx)
+
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Warnf("error closing response body: %s", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return 0, fmt.Errorf("unexpected response from %s: %s: %s", p, resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return 0, fmt.Errorf("error decoding response from %s: %s", p, err)
+	}
+
+	next, err := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid X-Consul-Index from %s: %s", p, err)
+	}
+
+	return nextIndex(index, next), nil
+}
+
+// nextIndex returns index for the next blocking query, index that
+// goes backwards is reset to make the next query return right away
+func nextIndex(previous, next uint64) uint64 {
+	if next < previous {
+		return 0
+	}
+
+	if next < 1 {
+		return 1
+	}
+
+	return next
+}
@@ -0,0 +1,209 @@
+package consul
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// watchWait is how long blocking queries wait for changes
+	watchWait = time.Minute
+	// watchRetry is how long to wait after failed queries
+	watchRetry = time.Second
+	// watchIdle is how long watcher keeps running without being used
+	watchIdle = time.Minute * 10
+)
+
+// Watcher keeps passing instances of services matching the filter
+// up to date with blocking queries, it runs in background after
+// the first use and stops when it is not used for a while
+type Watcher struct {
+	client *Client
+	filter func(tags []string) bool
+
+	running  bool
+	used     time.Time
+	attempt  chan struct{}
+	synced   bool
+	err      error
+	entries  map[string][]ServiceEntry
+	watching map[string]context.CancelFunc
+	mutex    sync.Mutex
+}
+
+// NewWatcher creates a watcher for services with tags matching the filter
+func NewWatcher(client *Client, filter func(tags []string) bool) *Watcher {
+	return &Watcher{
+		client: client,
+		filter: filter,
+	}
+}
+
+// Entries returns passing instances of watched services, watching
+// is started if needed and the first complete fetch is awaited
+func (w *Watcher) Entries(ctx context.Context) (map[string][]ServiceEntry, error) {
+	w.mutex.Lock()
+	w.used = time.Now()
+	if !w.running {
+		w.start()
+	}
+	attempt := w.attempt
+	w.mutex.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-attempt:
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.err != nil {
+		return nil, w.err
+	}
+
+	if !w.synced {
+		return nil, errors.New("consul services are not fetched yet")
+	}
+
+	r := make(map[string][]ServiceEntry, len(w.entries))
+	for service, entries := range w.entries {
+		r[service] = entries
+	}
+
+	return r, nil
+}
+
+// start starts watching in background, must be called with mutex held
+func (w *Watcher) start() {
+	w.running = true
+	w.attempt = make(chan struct{})
+	w.synced = false
+	w.err = nil
+	w.entries = map[string][]ServiceEntry{}
+	w.watching = map[string]context.CancelFunc{}
+
+	go w.run()
+}
+
+// run watches the list of services and starts watching
+// instances of new matching services
+func (w *Watcher) run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	attempted := false
+	index := uint64(0)
+
+	for {
+		w.mutex.Lock()
+		if time.Since(w.used) > watchIdle {
+			// services are not watched anymore when watcher can be restarted
+			cancel()
+			w.running = false
+			w.mutex.Unlock()
+			logger.Debugf("stopping idle watcher")
+			return
+		}
+		w.mutex.Unlock()
+
+		services, next, err := w.client.Services(ctx, index, watchWait)
+		if err != nil {
+			logger.Warnf("error fetching services: %s", err)
+
+			w.mutex.Lock()
+			w.err = err
+			w.mutex.Unlock()
+
+			if !attempted {
+				attempted = true
+				close(w.attempt)
+			}
+
+			time.Sleep(watchRetry)
+			continue
+		}
+
+		index = next
+
+		wg := sync.WaitGroup{}
+
+		w.mutex.Lock()
+		w.err = nil
+
+		for service, tags := range services {
+			if _, ok := w.watching[service]; ok || !w.filter(tags) {
+				continue
+			}
+
+			sctx, scancel := context.WithCancel(ctx)
+			w.watching[service] = scancel
+
+			wg.Add(1)
+			go w.watchService(sctx, service, wg.Done)
+		}
+
+		for service, scancel := range w.watching {
+			if tags, ok := services[service]; !ok || !w.filter(tags) {
+				scancel()
+				delete(w.watching, service)
+				delete(w.entries, service)
+			}
+		}
+		w.mutex.Unlock()
+
+		// new services are fetched before reporting them
+		wg.Wait()
+
+		w.mutex.Lock()
+		w.synced = true
+		w.mutex.Unlock()
+
+		if !attempted {
+			attempted = true
+			close(w.attempt)
+		}
+	}
+}
+
+// watchService keeps passing instances of the service up to date,
+// done is called after the first attempt to fetch them
+func (w *Watcher) watchService(ctx context.Context, service string, done func()) {
+	once := sync.Once{}
+	defer once.Do(done)
+
+	index := uint64(0)
+
+	for {
+		entries, next, err := w.client.PassingEntries(ctx, service, index, watchWait)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			logger.With("service", service).Warnf("error fetching instances: %s", err)
+			once.Do(done)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(watchRetry):
+			}
+
+			continue
+		}
+
+		index = next
+
+		w.mutex.Lock()
+		if ctx.Err() == nil {
+			w.entries[service] = entries
+		}
+		w.mutex.Unlock()
+
+		once.Do(done)
+	}
+}
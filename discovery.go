@@ -1,8 +1,11 @@
 package zoidberg
 
 import (
+	"time"
+
 	"github.com/bobrik/zoidberg/application"
 	"github.com/bobrik/zoidberg/balancer"
+	"github.com/bobrik/zoidberg/state"
 )
 
 // Discovery is a current known state of the world: load balancers and apps
@@ -10,3 +13,75 @@ type Discovery struct {
 	Balancers []balancer.Balancer `json:"balancers"`
 	Apps      application.Apps    `json:"apps"`
 }
+
+// DiscoveryResult is a discovery of a group with its origin:
+// time, generation of the discovery cycle and finder durations
+type DiscoveryResult struct {
+	*Discovery
+	Time       time.Time          `json:"time"`
+	Generation uint64             `json:"generation"`
+	Durations  map[string]float64 `json:"durations"`
+	Fresh      bool               `json:"fresh"`
+	// Weights are shares of traffic that versions of apps effectively get
+	Weights map[string]map[string]float64 `json:"weights,omitempty"`
+}
+
+// AppDiscovery is a discovered app with its versions and shares
+// of traffic that versions effectively get
+type AppDiscovery struct {
+	application.App
+	Versions   state.Versions     `json:"versions"`
+	Weights    map[string]float64 `json:"weights"`
+	Time       time.Time          `json:"time"`
+	Generation uint64             `json:"generation"`
+	Fresh      bool               `json:"fresh"`
+}
+
+// effectiveWeights returns shares of traffic that versions of the app get:
+// weight of a set version is assigned to each of its servers, so versions
+// split traffic by weight times the number of servers, without set versions
+// traffic is split evenly among all servers
+func effectiveWeights(app application.App, versions state.Versions) map[string]float64 {
+	servers := map[string]int{}
+	for _, s := range app.Servers {
+		servers[s.Version]++
+	}
+
+	r := make(map[string]float64, len(servers))
+	for v := range servers {
+		r[v] = 0
+	}
+
+	if len(versions) == 0 {
+		for v, n := range servers {
+			r[v] = float64(n) / float64(len(app.Servers))
+		}
+
+		return r
+	}
+
+	total := 0
+	for v, version := range versions {
+		if servers[v] > 0 && version.Weight > 0 {
+			total += version.Weight * servers[v]
+		}
+	}
+
+	for v, version := range versions {
+		if servers[v] > 0 && version.Weight > 0 {
+			r[v] = float64(version.Weight*servers[v]) / float64(total)
+		}
+	}
+
+	return r
+}
+
+// durationsInSeconds converts durations to seconds for serialization
+func durationsInSeconds(durations map[string]time.Duration) map[string]float64 {
+	r := make(map[string]float64, len(durations))
+	for n, d := range durations {
+		r[n] = d.Seconds()
+	}
+
+	return r
+}
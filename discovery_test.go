@@ -0,0 +1,58 @@
+package zoidberg
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bobrik/zoidberg/application"
+	"github.com/bobrik/zoidberg/state"
+)
+
+func TestEffectiveWeights(t *testing.T) {
+	app := application.App{
+		Name: "app",
+		Servers: []application.Server{
+			{Host: "a", Port: 1, Version: "1"},
+			{Host: "b", Port: 1, Version: "1"},
+			{Host: "c", Port: 1, Version: "1"},
+			{Host: "d", Port: 1, Version: "2"},
+		},
+	}
+
+	table := []struct {
+		versions state.Versions
+		weights  map[string]float64
+	}{
+		{
+			versions: nil,
+			weights:  map[string]float64{"1": 0.75, "2": 0.25},
+		},
+		{
+			versions: state.Versions{"1": {Weight: 1}, "2": {Weight: 3}},
+			weights:  map[string]float64{"1": 0.5, "2": 0.5},
+		},
+		{
+			versions: state.Versions{"1": {Weight: 1}, "2": {Weight: 1}},
+			weights:  map[string]float64{"1": 0.75, "2": 0.25},
+		},
+		{
+			versions: state.Versions{"1": {Weight: 1}, "2": {Weight: 6}},
+			weights:  map[string]float64{"1": 0.3333333333333333, "2": 0.6666666666666666},
+		},
+		{
+			versions: state.Versions{"1": {Weight: 1}, "3": {Weight: 1}},
+			weights:  map[string]float64{"1": 1, "2": 0},
+		},
+		{
+			versions: state.Versions{"2": {Weight: 0}, "1": {Weight: 5}},
+			weights:  map[string]float64{"1": 1, "2": 0},
+		},
+	}
+
+	for i, row := range table {
+		w := effectiveWeights(app, row.versions)
+		if !reflect.DeepEqual(w, row.weights) {
+			t.Errorf("row %d: expected: %v, got: %v", i, row.weights, w)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package dns
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when the name does not exist
+var ErrNotFound = errors.New("name not found")
+
+// Client queries a single dns server
+type Client struct {
+	server  string
+	timeout time.Duration
+}
+
+// NewClient creates a new client for the server in host[:port] format,
+// the first nameserver from /etc/resolv.conf is used if it's empty
+func NewClient(server string) (*Client, error) {
+	if server == "" {
+		s, err := systemResolver("/etc/resolv.conf")
+		if err != nil {
+			return nil, err
+		}
+
+		server = s
+	}
+
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+	}
+
+	return &Client{
+		server:  server,
+		timeout: time.Second * 5,
+	}, nil
+}
+
+// Server returns address of the server
+func (c *Client) Server() string {
+	return c.server
+}
+
+// Query sends a recursive query for records of the type, it is retried
+// over tcp if udp response is truncated
+func (c *Client) Query(ctx context.Context, name string, qtype uint16) (Message, error) {
+	id, err := queryID()
+	if err != nil {
+		return Message{}, err
+	}
+
+	q := Message{
+		ID:               id,
+		RecursionDesired: true,
+		Questions:        []Question{{Name: name, Type: qtype}},
+	}
+
+	r, err := c.exchange(ctx, "udp", q)
+	if err == nil && r.Truncated {
+		r, err = c.exchange(ctx, "tcp", q)
+	}
+
+	if err != nil {
+		return r, err
+	}
+
+	switch r.RCode {
+	case RCodeSuccess:
+		return r, nil
+	case RCodeNotFound:
+		return r, fmt.Errorf("error resolving %s: %s", name, ErrNotFound)
+	default:
+		return r, fmt.Errorf("error resolving %s: server %s responded with code %d", name, c.server, r.RCode)
+	}
+}
+
+// queryID returns a random id of a query, ids are unpredictable,
+// so responses to them are harder to spoof
+func queryID() (uint16, error) {
+	b := [2]byte{}
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, fmt.Errorf("error generating query id: %s", err)
+	}
+
+	return binary.BigEndian.Uint16(b[:]), nil
+}
+
+// exchange sends the query and reads the response over the network
+func (c *Client) exchange(ctx context.Context, network string, q Message) (Message, error) {
+	b, err := q.Pack()
+	if err != nil {
+		return Message{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	d := net.Dialer{}
+	conn, err := d.DialContext(ctx, network, c.server)
+	if err != nil {
+		return Message{}, err
+	}
+
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "tcp" {
+		b = append(appendUint16(nil, uint16(len(b))), b...)
+	}
+
+	if _, err = conn.Write(b); err != nil {
+		return Message{}, err
+	}
+
+	for {
+		buf, err := read(conn, network)
+		if err != nil {
+			return Message{}, err
+		}
+
+		r, err := Unpack(buf)
+		if err != nil {
+			return Message{}, fmt.Errorf("error decoding response from %s: %s", c.server, err)
+		}
+
+		// responses to other queries may arrive on udp sockets
+		if !r.Response || r.ID != q.ID {
+			if network == "tcp" {
+				return Message{}, fmt.Errorf("unexpected response from %s", c.server)
+			}
+
+			continue
+		}
+
+		return r, nil
+	}
+}
+
+// read reads a single message from the connection
+func read(conn net.Conn, network string) ([]byte, error) {
+	if network == "udp" {
+		buf := make([]byte, 65535)
+		n, err := conn.Read(buf)
+		return buf[:n], err
+	}
+
+	l := make([]byte, 2)
+	if _, err := io.ReadFull(conn, l); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, binary.BigEndian.Uint16(l))
+	_, err := io.ReadFull(conn, buf)
+
+	return buf, err
+}
+
+// systemResolver returns the first nameserver from resolv.conf
+func systemResolver(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) > 1 && fields[0] == "nameserver" {
+			return fields[1], nil
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("no nameservers in %s", file)
+}
@@ -0,0 +1,143 @@
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+// serveStub answers queries over udp and tcp on the same port, udp
+// responses are truncated if there are more than one answer
+func serveStub(t *testing.T, answers []Record) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		pc.Close()
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		pc.Close()
+		l.Close()
+	})
+
+	respond := func(b []byte, truncate bool) []byte {
+		q, err := Unpack(b)
+		if err != nil {
+			t.Errorf("error decoding query: %s", err)
+			return nil
+		}
+
+		r := Message{ID: q.ID, Response: true, Questions: q.Questions}
+		if truncate && len(answers) > 1 {
+			r.Truncated = true
+		} else {
+			r.Answers = answers
+		}
+
+		p, err := r.Pack()
+		if err != nil {
+			t.Errorf("error encoding response: %s", err)
+		}
+
+		return p
+	}
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			pc.WriteTo(respond(buf[:n], true), addr)
+		}
+	}()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			l := make([]byte, 2)
+			if _, err = io.ReadFull(conn, l); err == nil {
+				b := make([]byte, binary.BigEndian.Uint16(l))
+				if _, err = io.ReadFull(conn, b); err == nil {
+					p := respond(b, false)
+					conn.Write(append(appendUint16(nil, uint16(len(p))), p...))
+				}
+			}
+
+			conn.Close()
+		}
+	}()
+
+	return pc.LocalAddr().String()
+}
+
+func TestClientQuery(t *testing.T) {
+	table := []struct {
+		answers []Record
+	}{
+		{
+			answers: []Record{
+				{Name: "lb.example.com.", Type: TypeA, TTL: 10, IP: net.ParseIP("10.0.0.1").To4()},
+			},
+		},
+		{
+			answers: []Record{
+				{Name: "lb.example.com.", Type: TypeA, TTL: 10, IP: net.ParseIP("10.0.0.1").To4()},
+				{Name: "lb.example.com.", Type: TypeA, TTL: 10, IP: net.ParseIP("10.0.0.2").To4()},
+			},
+		},
+	}
+
+	for i, row := range table {
+		c, err := NewClient(serveStub(t, row.answers))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := c.Query(context.Background(), "lb.example.com", TypeA)
+		if err != nil {
+			t.Errorf("row %d: unexpected error: %s", i, err)
+			continue
+		}
+
+		if len(m.Answers) != len(row.answers) {
+			t.Errorf("row %d: expected: %d answers, got: %v", i, len(row.answers), m.Answers)
+		}
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	table := []struct {
+		server   string
+		expected string
+	}{
+		{server: "10.0.0.1", expected: "10.0.0.1:53"},
+		{server: "10.0.0.1:5353", expected: "10.0.0.1:5353"},
+		{server: "2001:db8::1", expected: "[2001:db8::1]:53"},
+		{server: "[2001:db8::1]:5353", expected: "[2001:db8::1]:5353"},
+	}
+
+	for _, row := range table {
+		c, err := NewClient(row.server)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if c.Server() != row.expected {
+			t.Errorf("expected: %q, got: %q", row.expected, c.Server())
+		}
+	}
+}
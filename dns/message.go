@@ -0,0 +1,377 @@
+package dns
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Record types supported by the client
+const (
+	TypeA     uint16 = 1
+	TypeCNAME uint16 = 5
+	TypeAAAA  uint16 = 28
+	TypeSRV   uint16 = 33
+)
+
+// Response codes reported by servers
+const (
+	RCodeSuccess  = 0
+	RCodeNotFound = 3
+)
+
+// classINET is the only class of records supported
+const classINET = 1
+
+// errTruncated is returned when message ends unexpectedly
+var errTruncated = errors.New("truncated message")
+
+// Message is a dns query or response
+type Message struct {
+	ID                 uint16
+	Response           bool
+	Truncated          bool
+	RecursionDesired   bool
+	RecursionAvailable bool
+	RCode              int
+	Questions          []Question
+	Answers            []Record
+	Additionals        []Record
+}
+
+// Question is a question of a dns message
+type Question struct {
+	Name string
+	Type uint16
+}
+
+// Record is a resource record, only fields of its type are set
+type Record struct {
+	Name string
+	Type uint16
+	TTL  uint32
+
+	// A and AAAA records
+	IP net.IP
+
+	// SRV and CNAME records
+	Target string
+
+	// SRV records
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+}
+
+// Pack encodes message in wire format without name compression
+func (m Message) Pack() ([]byte, error) {
+	b := make([]byte, 12, 512)
+
+	flags := uint16(m.RCode & 0xf)
+	if m.Response {
+		flags |= 1 << 15
+	}
+
+	if m.Truncated {
+		flags |= 1 << 9
+	}
+
+	if m.RecursionDesired {
+		flags |= 1 << 8
+	}
+
+	if m.RecursionAvailable {
+		flags |= 1 << 7
+	}
+
+	binary.BigEndian.PutUint16(b[0:], m.ID)
+	binary.BigEndian.PutUint16(b[2:], flags)
+	binary.BigEndian.PutUint16(b[4:], uint16(len(m.Questions)))
+	binary.BigEndian.PutUint16(b[6:], uint16(len(m.Answers)))
+	binary.BigEndian.PutUint16(b[10:], uint16(len(m.Additionals)))
+
+	var err error
+
+	for _, q := range m.Questions {
+		b, err = packName(b, q.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		b = appendUint16(b, q.Type)
+		b = appendUint16(b, classINET)
+	}
+
+	for _, r := range append(append([]Record{}, m.Answers...), m.Additionals...) {
+		b, err = packRecord(b, r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// packRecord appends record in wire format
+func packRecord(b []byte, r Record) ([]byte, error) {
+	b, err := packName(b, r.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	rdata := []byte{}
+
+	switch r.Type {
+	case TypeA:
+		ip := r.IP.To4()
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ipv4 address %s in a record", r.IP)
+		}
+
+		rdata = append(rdata, ip...)
+	case TypeAAAA:
+		if r.IP.To16() == nil || r.IP.To4() != nil {
+			return nil, fmt.Errorf("invalid ipv6 address %s in aaaa record", r.IP)
+		}
+
+		rdata = append(rdata, r.IP.To16()...)
+	case TypeCNAME:
+		rdata, err = packName(rdata, r.Target)
+	case TypeSRV:
+		rdata = appendUint16(rdata, r.Priority)
+		rdata = appendUint16(rdata, r.Weight)
+		rdata = appendUint16(rdata, r.Port)
+		rdata, err = packName(rdata, r.Target)
+	default:
+		return nil, fmt.Errorf("unsupported record type %d", r.Type)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	b = appendUint16(b, r.Type)
+	b = appendUint16(b, classINET)
+	b = appendUint32(b, r.TTL)
+	b = appendUint16(b, uint16(len(rdata)))
+
+	return append(b, rdata...), nil
+}
+
+// appendUint16 appends v in network byte order
+func appendUint16(b []byte, v uint16) []byte {
+	n := [2]byte{}
+	binary.BigEndian.PutUint16(n[:], v)
+	return append(b, n[:]...)
+}
+
+// appendUint32 appends v in network byte order
+func appendUint32(b []byte, v uint32) []byte {
+	n := [4]byte{}
+	binary.BigEndian.PutUint32(n[:], v)
+	return append(b, n[:]...)
+}
+
+// packName appends name as a sequence of labels
+func packName(b []byte, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return append(b, 0), nil
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 {
+			return nil, fmt.Errorf("invalid name %q", name)
+		}
+
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+
+	return append(b, 0), nil
+}
+
+// Unpack decodes message from wire format, records
+// of unsupported types are skipped
+func Unpack(b []byte) (Message, error) {
+	m := Message{}
+
+	if len(b) < 12 {
+		return m, errTruncated
+	}
+
+	m.ID = binary.BigEndian.Uint16(b[0:])
+	flags := binary.BigEndian.Uint16(b[2:])
+	m.Response = flags&(1<<15) != 0
+	m.Truncated = flags&(1<<9) != 0
+	m.RecursionDesired = flags&(1<<8) != 0
+	m.RecursionAvailable = flags&(1<<7) != 0
+	m.RCode = int(flags & 0xf)
+
+	qd := int(binary.BigEndian.Uint16(b[4:]))
+	an := int(binary.BigEndian.Uint16(b[6:]))
+	ns := int(binary.BigEndian.Uint16(b[8:]))
+	ar := int(binary.BigEndian.Uint16(b[10:]))
+
+	off := 12
+
+	for i := 0; i < qd; i++ {
+		name, n, err := unpackName(b, off)
+		if err != nil {
+			return m, err
+		}
+
+		if n+4 > len(b) {
+			return m, errTruncated
+		}
+
+		m.Questions = append(m.Questions, Question{
+			Name: name,
+			Type: binary.BigEndian.Uint16(b[n:]),
+		})
+
+		off = n + 4
+	}
+
+	for i := 0; i < an+ns+ar; i++ {
+		r, n, err := unpackRecord(b, off)
+		if err != nil {
+			return m, err
+		}
+
+		off = n
+
+		if r == nil {
+			continue
+		}
+
+		switch {
+		case i < an:
+			m.Answers = append(m.Answers, *r)
+		case i >= an+ns:
+			m.Additionals = append(m.Additionals, *r)
+		}
+	}
+
+	return m, nil
+}
+
+// unpackRecord decodes record at the offset and returns offset after it,
+// nil record is returned for records of unsupported types
+func unpackRecord(b []byte, off int) (*Record, int, error) {
+	name, off, err := unpackName(b, off)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if off+10 > len(b) {
+		return nil, 0, errTruncated
+	}
+
+	r := &Record{
+		Name: name,
+		Type: binary.BigEndian.Uint16(b[off:]),
+		TTL:  binary.BigEndian.Uint32(b[off+4:]),
+	}
+
+	class := binary.BigEndian.Uint16(b[off+2:])
+	length := int(binary.BigEndian.Uint16(b[off+8:]))
+
+	off += 10
+	end := off + length
+
+	if end > len(b) {
+		return nil, 0, errTruncated
+	}
+
+	if class != classINET {
+		return nil, end, nil
+	}
+
+	switch r.Type {
+	case TypeA:
+		if length != net.IPv4len {
+			return nil, 0, fmt.Errorf("invalid a record length %d", length)
+		}
+
+		r.IP = net.IP(append([]byte{}, b[off:end]...))
+	case TypeAAAA:
+		if length != net.IPv6len {
+			return nil, 0, fmt.Errorf("invalid aaaa record length %d", length)
+		}
+
+		r.IP = net.IP(append([]byte{}, b[off:end]...))
+	case TypeCNAME:
+		r.Target, _, err = unpackName(b, off)
+	case TypeSRV:
+		if length < 7 {
+			return nil, 0, fmt.Errorf("invalid srv record length %d", length)
+		}
+
+		r.Priority = binary.BigEndian.Uint16(b[off:])
+		r.Weight = binary.BigEndian.Uint16(b[off+2:])
+		r.Port = binary.BigEndian.Uint16(b[off+4:])
+		r.Target, _, err = unpackName(b, off+6)
+	default:
+		return nil, end, nil
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return r, end, nil
+}
+
+// unpackName decodes possibly compressed name at the offset
+// and returns offset after it in the original message
+func unpackName(b []byte, off int) (string, int, error) {
+	labels := []string{}
+	end := -1
+
+	// every pointer must point backwards, which rules out loops
+	limit := off
+
+	for {
+		if off >= len(b) {
+			return "", 0, errTruncated
+		}
+
+		l := int(b[off])
+
+		switch {
+		case l == 0:
+			if end < 0 {
+				end = off + 1
+			}
+
+			return strings.Join(labels, ".") + ".", end, nil
+		case l&0xc0 == 0xc0:
+			if off+1 >= len(b) {
+				return "", 0, errTruncated
+			}
+
+			if end < 0 {
+				end = off + 2
+			}
+
+			ptr := int(binary.BigEndian.Uint16(b[off:]) & 0x3fff)
+			if ptr >= limit {
+				return "", 0, errors.New("invalid name compression pointer")
+			}
+
+			off, limit = ptr, ptr
+		case l > 63:
+			return "", 0, fmt.Errorf("invalid label length %d", l)
+		default:
+			if off+1+l > len(b) {
+				return "", 0, errTruncated
+			}
+
+			labels = append(labels, string(b[off+1:off+1+l]))
+			off += 1 + l
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package dns
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestPackUnpack(t *testing.T) {
+	m := Message{
+		ID:                 42,
+		Response:           true,
+		RecursionDesired:   true,
+		RecursionAvailable: true,
+		RCode:              RCodeSuccess,
+		Questions:          []Question{{Name: "_http._tcp.lb.example.com.", Type: TypeSRV}},
+		Answers: []Record{
+			{Name: "_http._tcp.lb.example.com.", Type: TypeSRV, TTL: 30, Priority: 1, Weight: 2, Port: 8080, Target: "lb1.example.com."},
+			{Name: "www.example.com.", Type: TypeCNAME, TTL: 60, Target: "lb.example.com."},
+		},
+		Additionals: []Record{
+			{Name: "lb1.example.com.", Type: TypeA, TTL: 10, IP: net.ParseIP("10.0.0.1").To4()},
+			{Name: "lb1.example.com.", Type: TypeAAAA, TTL: 20, IP: net.ParseIP("2001:db8::1")},
+		},
+	}
+
+	b, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := Unpack(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(u, m) {
+		t.Errorf("expected: %+v, got: %+v", m, u)
+	}
+}
+
+func TestUnpackName(t *testing.T) {
+	table := []struct {
+		b    []byte
+		off  int
+		name string
+		end  int
+		err  bool
+	}{
+		{
+			b:    []byte{2, 'l', 'b', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0},
+			name: "lb.example.",
+			end:  12,
+		},
+		{
+			b:    []byte{7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0, 2, 'l', 'b', 0xc0, 0},
+			off:  9,
+			name: "lb.example.",
+			end:  14,
+		},
+		{
+			b:    []byte{0},
+			name: ".",
+			end:  1,
+		},
+		{
+			b:   []byte{0xc0, 0},
+			err: true,
+		},
+		{
+			b:   []byte{2, 'l', 'b', 0xc0, 3},
+			err: true,
+		},
+		{
+			b:   []byte{7, 'e', 'x'},
+			err: true,
+		},
+	}
+
+	for i, row := range table {
+		name, end, err := unpackName(row.b, row.off)
+		if row.err {
+			if err == nil {
+				t.Errorf("row %d: expected error, got: %q", i, name)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("row %d: unexpected error: %s", i, err)
+			continue
+		}
+
+		if name != row.name || end != row.end {
+			t.Errorf("row %d: expected: %q at %d, got: %q at %d", i, row.name, row.end, name, end)
+		}
+	}
+}
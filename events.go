@@ -0,0 +1,278 @@
+package zoidberg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bobrik/zoidberg/application"
+	"github.com/bobrik/zoidberg/state"
+)
+
+const (
+	// EventAppAdded is emitted when an app is discovered for the first time
+	EventAppAdded = "app_added"
+	// EventAppRemoved is emitted when an app is no longer discovered
+	EventAppRemoved = "app_removed"
+	// EventServersChanged is emitted when servers of an app change
+	EventServersChanged = "servers_changed"
+	// EventVersionsChanged is emitted when versions of an app are set,
+	// which is how rollouts shift traffic step by step
+	EventVersionsChanged = "versions_changed"
+	// EventVersionsDeleted is emitted when versions of an app are deleted
+	EventVersionsDeleted = "versions_deleted"
+	// EventBalancerUpdated is emitted when balancer accepts state
+	EventBalancerUpdated = "balancer_updated"
+	// EventBalancerFailed is emitted when balancer fails to accept state
+	EventBalancerFailed = "balancer_failed"
+)
+
+// eventHistory is the number of recent events kept for resuming streams
+const eventHistory = 1024
+
+// eventKeepalive is how often comments are sent to idle streams
+const eventKeepalive = time.Second * 15
+
+// Event is a change in discovery or state of a balancer group
+type Event struct {
+	ID       uint64               `json:"id"`
+	Type     string               `json:"type"`
+	Time     time.Time            `json:"time"`
+	Group    string               `json:"group"`
+	App      string               `json:"app,omitempty"`
+	Balancer string               `json:"balancer,omitempty"`
+	Servers  []application.Server `json:"servers,omitempty"`
+	Versions state.Versions       `json:"versions,omitempty"`
+	Error    string               `json:"error,omitempty"`
+	Identity string               `json:"identity,omitempty"`
+}
+
+// eventBroker keeps recent events and delivers new ones to subscribers
+type eventBroker struct {
+	next        uint64
+	history     []Event
+	subscribers map[chan Event]bool
+	closed      bool
+	mutex       sync.Mutex
+}
+
+// newEventBroker creates a new broker, event ids start from current
+// time in nanoseconds, so they keep increasing across restarts
+func newEventBroker() *eventBroker {
+	return &eventBroker{
+		next:        uint64(time.Now().UnixNano()),
+		subscribers: map[chan Event]bool{},
+	}
+}
+
+// publish assigns id to the event and delivers it to subscribers,
+// subscribers that can't keep up are disconnected to resume later
+func (b *eventBroker) publish(e Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.next++
+	e.ID = b.next
+	e.Time = time.Now()
+
+	b.history = append(b.history, e)
+	if len(b.history) > eventHistory {
+		b.history = b.history[len(b.history)-eventHistory:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe returns kept events after the specified id and a channel
+// with new events, the channel is closed when subscriber is dropped
+func (b *eventBroker) subscribe(after uint64) ([]Event, chan Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	backlog := []Event{}
+	for _, e := range b.history {
+		if e.ID > after {
+			backlog = append(backlog, e)
+		}
+	}
+
+	ch := make(chan Event, eventHistory)
+	if b.closed {
+		close(ch)
+		return backlog, ch
+	}
+
+	b.subscribers[ch] = true
+
+	return backlog, ch
+}
+
+// unsubscribe stops delivering events to the channel
+func (b *eventBroker) unsubscribe(ch chan Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.subscribers[ch] {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// close disconnects all subscribers and stops accepting new events
+func (b *eventBroker) close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for ch := range b.subscribers {
+		close(ch)
+	}
+
+	b.subscribers = map[chan Event]bool{}
+	b.closed = true
+}
+
+// publishDiscoveryChanges publishes changes of apps between discoveries
+func (e *Explorer) publishDiscoveryChanges(previous, current map[string]*Discovery) {
+	for n, d := range current {
+		p, ok := previous[n]
+		if !ok {
+			p = &Discovery{}
+		}
+
+		for name, app := range d.Apps {
+			old, ok := p.Apps[name]
+			if !ok {
+				e.events.publish(Event{Type: EventAppAdded, Group: n, App: name})
+			}
+
+			if !reflect.DeepEqual(sortedServers(old.Servers), sortedServers(app.Servers)) {
+				e.events.publish(Event{Type: EventServersChanged, Group: n, App: name, Servers: app.Servers})
+			}
+		}
+
+		for name := range p.Apps {
+			if _, ok := d.Apps[name]; !ok {
+				e.events.publish(Event{Type: EventAppRemoved, Group: n, App: name})
+			}
+		}
+	}
+}
+
+// sortedServers returns a sorted copy of servers for comparison
+func sortedServers(servers []application.Server) []application.Server {
+	r := make([]application.Server, len(servers))
+	copy(r, servers)
+
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].String() != r[j].String() {
+			return r[i].String() < r[j].String()
+		}
+
+		return r[i].Version < r[j].Version
+	})
+
+	return r
+}
+
+// serveEvents streams events as server-sent events, Last-Event-ID
+// header or query parameter resumes the stream after that event
+func (e *Explorer) serveEvents(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		http.Error(w, "expected GET", http.StatusBadRequest)
+		return
+	}
+
+	f, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	last := req.Header.Get("Last-Event-ID")
+	if last == "" {
+		last = req.URL.Query().Get("last_event_id")
+	}
+
+	after := uint64(0)
+	if last != "" {
+		id, err := strconv.ParseUint(last, 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid last event id: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		after = id
+	}
+
+	group := req.URL.Query().Get("group")
+
+	backlog, ch := e.events.subscribe(after)
+	defer e.events.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(ev Event) error {
+		if group != "" && ev.Group != group {
+			return nil
+		}
+
+		b, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, b)
+		return err
+	}
+
+	for _, ev := range backlog {
+		if err := send(ev); err != nil {
+			return
+		}
+	}
+
+	f.Flush()
+
+	keepalive := time.NewTicker(eventKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			if err := send(ev); err != nil {
+				logger.Debugf("error sending event: %s", err)
+				return
+			}
+		}
+
+		f.Flush()
+	}
+}
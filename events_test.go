@@ -0,0 +1,107 @@
+package zoidberg
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bobrik/zoidberg/application"
+)
+
+func TestPublishDiscoveryChanges(t *testing.T) {
+	a := application.Server{Host: "a", Port: 1}
+	b := application.Server{Host: "b", Port: 2}
+
+	table := []struct {
+		previous map[string]*Discovery
+		current  map[string]*Discovery
+		types    []string
+	}{
+		{
+			previous: nil,
+			current: map[string]*Discovery{
+				"g": {Apps: application.Apps{"app": {Name: "app", Servers: []application.Server{a}}}},
+			},
+			types: []string{EventAppAdded, EventServersChanged},
+		},
+		{
+			previous: map[string]*Discovery{
+				"g": {Apps: application.Apps{"app": {Name: "app", Servers: []application.Server{a, b}}}},
+			},
+			current: map[string]*Discovery{
+				"g": {Apps: application.Apps{"app": {Name: "app", Servers: []application.Server{b, a}}}},
+			},
+			types: []string{},
+		},
+		{
+			previous: map[string]*Discovery{
+				"g": {Apps: application.Apps{"app": {Name: "app", Servers: []application.Server{a}}}},
+			},
+			current: map[string]*Discovery{
+				"g": {Apps: application.Apps{"app": {Name: "app", Servers: []application.Server{b}}}},
+			},
+			types: []string{EventServersChanged},
+		},
+		{
+			previous: map[string]*Discovery{
+				"g": {Apps: application.Apps{"app": {Name: "app", Servers: []application.Server{a}}}},
+			},
+			current: map[string]*Discovery{
+				"g": {Apps: application.Apps{}},
+			},
+			types: []string{EventAppRemoved},
+		},
+	}
+
+	for i, row := range table {
+		e := &Explorer{events: newEventBroker()}
+		e.publishDiscoveryChanges(row.previous, row.current)
+
+		events, _ := e.events.subscribe(0)
+
+		types := []string{}
+		for _, ev := range events {
+			types = append(types, ev.Type)
+		}
+
+		if !reflect.DeepEqual(types, row.types) {
+			t.Errorf("row %d: expected: %v, got: %v", i, row.types, types)
+		}
+	}
+}
+
+func TestEventBrokerResume(t *testing.T) {
+	b := newEventBroker()
+
+	for i := 0; i < eventHistory+10; i++ {
+		b.publish(Event{Type: EventBalancerUpdated})
+	}
+
+	all, ch := b.subscribe(0)
+	if len(all) != eventHistory {
+		t.Errorf("expected: %d, got: %d", eventHistory, len(all))
+	}
+
+	for i := 1; i < len(all); i++ {
+		if all[i].ID <= all[i-1].ID {
+			t.Errorf("expected increasing ids, got: %d after %d", all[i].ID, all[i-1].ID)
+		}
+	}
+
+	rest, _ := b.subscribe(all[len(all)-3].ID)
+	if len(rest) != 2 {
+		t.Errorf("expected: %d, got: %d", 2, len(rest))
+	}
+
+	b.publish(Event{Type: EventBalancerFailed})
+
+	ev := <-ch
+	if ev.Type != EventBalancerFailed || ev.ID != all[len(all)-1].ID+1 {
+		t.Errorf("expected next %s event, got: %+v", EventBalancerFailed, ev)
+	}
+
+	b.close()
+
+	if _, ok := <-ch; ok {
+		t.Errorf("expected closed channel after close")
+	}
+}
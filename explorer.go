@@ -1,179 +1,732 @@
 package zoidberg
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
 	"path"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/bobrik/zoidberg/application"
+	"github.com/bobrik/zoidberg/auth"
 	"github.com/bobrik/zoidberg/balancer"
+	"github.com/bobrik/zoidberg/logging"
 	"github.com/bobrik/zoidberg/state"
 	"github.com/samuel/go-zookeeper/zk"
 )
 
+var logger = logging.Component("explorer")
+
+// Group is a group of load balancers with finders for their apps
+// and balancers and zookeeper path to persist versioning information,
+// state found at legacy path is moved if the group has no state yet
+type Group struct {
+	Name                string
+	ApplicationFinder   application.Finder
+	BalancerFinder      balancer.Finder
+	ZookeeperPath       string
+	LegacyZookeeperPath string
+}
+
+// group is a balancer group managed by explorer
+type group struct {
+	name    string
+	af      application.Finder
+	bf      balancer.Finder
+	zp      string
+	state   state.State
+	updated map[string]update
+	failed  map[string]time.Time
+
+	seen         map[string]time.Time
+	seenVersions map[string]map[string]time.Time
+}
+
 // Explorer constantly updates cluster state and notifies Balancers
 type Explorer struct {
 	name      string
-	af        application.Finder
-	bf        balancer.Finder
+	groups    map[string]*group
 	zookeeper *zk.Conn
-	zp        string
-	state     state.State
-	updated   map[string]update
 	interval  time.Duration
 	laziness  time.Duration
 	mutex     sync.Mutex
+	stop      chan struct{}
+	kick      chan struct{}
+	stopOnce  sync.Once
+	running   sync.WaitGroup
+
+	discoveries map[string]*Discovery
+	discovered  time.Time
+	generation  uint64
+	durations   map[string]time.Duration
+	pushed      time.Time
+	started     time.Time
+	thresholds  HealthThresholds
+	session     zkSession
+	metrics     *explorerMetrics
+	events      *eventBroker
+	auth        *auth.Auth
+	reaper      ReaperConfig
 }
 
 // NewExplorer creates a new Explorer instance with a name,
-// application and balancer finders and zookeeper connection
-// to persist versioning information
-func NewExplorer(name string, af application.Finder, bf balancer.Finder, zc *zk.Conn, zp string, interval, laziness time.Duration) (*Explorer, error) {
-	s := state.State{}
+// balancer groups and zookeeper connection to persist
+// versioning information
+func NewExplorer(name string, groups []Group, zc *zk.Conn, interval, laziness time.Duration) (*Explorer, error) {
+	started := time.Now()
+
+	e := &Explorer{
+		zookeeper: zc,
+		groups:    map[string]*group{},
+		mutex:     sync.Mutex{},
+		stop:      make(chan struct{}),
+		kick:      make(chan struct{}, 1),
+		started:   started,
+		events:    newEventBroker(),
+		// there is no session until the first session event says otherwise
+		session: zkSession{
+			state: zk.StateUnknown,
+			lost:  started,
+		},
+		thresholds: HealthThresholds{
+			FailingBalancers: -1,
+		},
+	}
 
-	ss, _, err := zc.Get(zp)
+	e.metrics = newExplorerMetrics(e)
+
+	err := e.Reconfigure(name, groups, interval, laziness)
 	if err != nil {
-		if err != zk.ErrNoNode {
-			return nil, err
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Reconfigure replaces name, balancer groups and intervals of the explorer,
+// groups that keep their name and zookeeper path also keep their state
+func (e *Explorer) Reconfigure(name string, groups []Group, interval, laziness time.Duration) error {
+	if len(groups) == 0 {
+		return errors.New("no balancer groups specified")
+	}
+
+	current := e.groupList()
+
+	gs := make(map[string]*group, len(groups))
+	for _, g := range groups {
+		if g.Name == "" || strings.Contains(g.Name, "/") {
+			return fmt.Errorf("invalid balancer group name %q", g.Name)
+		}
+
+		if _, ok := gs[g.Name]; ok {
+			return fmt.Errorf("duplicate balancer group %q", g.Name)
+		}
+
+		if c, ok := current[g.Name]; ok && c.zp == g.ZookeeperPath {
+			gs[g.Name] = c
+			continue
+		}
+
+		if g.LegacyZookeeperPath != "" {
+			err := e.setUpZkPath(path.Dir(g.ZookeeperPath))
+			if err != nil {
+				return err
+			}
+
+			moved, err := migrateState(e.zookeeper, g.ZookeeperPath, g.LegacyZookeeperPath)
+			if err != nil {
+				return fmt.Errorf("error moving state of balancer group %q: %s", g.Name, err)
+			}
+
+			if moved {
+				logger.With("group", g.Name).Infof("moved state from %s to %s", g.LegacyZookeeperPath, g.ZookeeperPath)
+			}
+		}
+
+		s, err := loadState(e.zookeeper, g.ZookeeperPath)
+		if err != nil {
+			return err
+		}
+
+		gs[g.Name] = &group{
+			name:    g.Name,
+			zp:      g.ZookeeperPath,
+			state:   s,
+			updated: map[string]update{},
+			failed:  map[string]time.Time{},
+
+			seen:         map[string]time.Time{},
+			seenVersions: map[string]map[string]time.Time{},
+		}
+	}
+
+	e.mutex.Lock()
+
+	replaced := []interface{}{}
+	gone := map[string][]string{}
+	for n, g := range e.groups {
+		replaced = append(replaced, g.af, g.bf)
+
+		// balancers of groups that are not kept are gone with them
+		if gs[n] != g {
+			for b := range g.updated {
+				gone[n] = append(gone[n], b)
+			}
+
+			for b := range g.failed {
+				gone[n] = append(gone[n], b)
+			}
+		}
+	}
+
+	finders := []interface{}{}
+	for _, g := range groups {
+		gs[g.Name].af = g.ApplicationFinder
+		gs[g.Name].bf = g.BalancerFinder
+		finders = append(finders, g.ApplicationFinder, g.BalancerFinder)
+	}
+
+	e.name = name
+	e.groups = gs
+	e.interval = interval
+	e.laziness = laziness
+
+	e.mutex.Unlock()
+
+	for n, balancers := range gone {
+		for _, b := range balancers {
+			e.metrics.forgetBalancer(n, b)
+		}
+	}
+
+	closeFinders(replaced, finders)
+
+	return nil
+}
+
+// closeFinders closes replaced finders that hold resources, like
+// watched files, unless they are still used by current finders
+func closeFinders(replaced, current []interface{}) {
+	for _, f := range replaced {
+		c, ok := f.(io.Closer)
+		if !ok {
+			continue
+		}
+
+		used := false
+		for _, n := range current {
+			if n, ok := n.(io.Closer); ok && n == c {
+				used = true
+				break
+			}
+		}
+
+		if used {
+			continue
 		}
 
+		err := c.Close()
+		if err != nil {
+			logger.Warnf("error closing replaced finder: %s", err)
+		}
+	}
+}
+
+// groupList returns currently managed balancer groups
+func (e *Explorer) groupList() map[string]*group {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	r := make(map[string]*group, len(e.groups))
+	for n, g := range e.groups {
+		r[n] = g
+	}
+
+	return r
+}
+
+// lookupGroup returns balancer group by name, empty name
+// returns the only group if there is only one group
+func (e *Explorer) lookupGroup(name string) *group {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if name == "" {
+		if len(e.groups) != 1 {
+			return nil
+		}
+
+		for _, g := range e.groups {
+			return g
+		}
+	}
+
+	return e.groups[name]
+}
+
+// loadState loads version state from zookeeper
+func loadState(zs stateStore, zp string) (state.State, error) {
+	s := state.State{}
+
+	ss, _, err := zs.Get(zp)
+	if err != nil && err != zk.ErrNoNode {
+		return s, err
+	}
+
+	if len(ss) == 0 {
 		s.Versions = map[string]state.Versions{}
 	} else {
 		err := json.Unmarshal(ss, &s)
 		if err != nil {
-			return nil, err
+			return s, err
 		}
 	}
 
-	return &Explorer{
-		name:      name,
-		af:        af,
-		bf:        bf,
-		zookeeper: zc,
-		zp:        zp,
-		state:     s,
-		updated:   map[string]update{},
-		interval:  interval,
-		laziness:  laziness,
-		mutex:     sync.Mutex{},
-	}, nil
+	return s, nil
+}
+
+// stateStore is the part of zookeeper client that keeps state
+type stateStore interface {
+	Get(path string) ([]byte, *zk.Stat, error)
+	Multi(ops ...interface{}) ([]zk.MultiResponse, error)
+}
+
+// migrateState moves state from legacy path to the specified path
+// if there is no state at the specified path yet, legacy path is
+// emptied rather than removed as it is the parent of group paths
+func migrateState(zs stateStore, zp, legacy string) (bool, error) {
+	_, _, err := zs.Get(zp)
+	if err != zk.ErrNoNode {
+		return false, err
+	}
+
+	b, stat, err := zs.Get(legacy)
+	if err == zk.ErrNoNode || (err == nil && len(b) == 0) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	_, err = zs.Multi(
+		&zk.CreateRequest{Path: zp, Data: b, Acl: zk.WorldACL(zk.PermAll)},
+		&zk.SetDataRequest{Path: legacy, Data: []byte{}, Version: stat.Version},
+	)
+
+	return err == nil, err
 }
 
 // Run launches explorer's main loop that fetches state
-// and updates load balancers' state
-func (e *Explorer) Run() error {
+// and updates load balancers' state. Cancelling the context
+// aborts requests in flight, use Shutdown to stop gracefully.
+func (e *Explorer) Run(ctx context.Context) error {
+	e.running.Add(1)
+	defer e.running.Done()
+
 	for {
-		time.Sleep(e.interval)
+		e.mutex.Lock()
+		interval := e.interval
+		e.mutex.Unlock()
 
-		d, err := e.discover()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-e.stop:
+			return nil
+		case <-e.kick:
+		case <-time.After(interval):
+		}
+
+		d, durations, err := e.discover(ctx)
 		if err != nil {
 			return err
 		}
 
-		e.updateBalancers(d)
+		e.mutex.Lock()
+		previous := e.discoveries
+		e.discoveries = d
+		e.discovered = time.Now()
+		e.durations = durations
+		e.generation++
+		e.mutex.Unlock()
+
+		e.publishDiscoveryChanges(previous, d)
+
+		e.markSeen(d, time.Now())
+		e.reap(time.Now())
+		e.forget(time.Now())
+
+		e.updateBalancers(ctx, d)
+	}
+}
+
+// triggerCycle makes the main loop run the next cycle right away,
+// pushes only happen there, so they are never concurrent or reordered
+func (e *Explorer) triggerCycle() {
+	select {
+	case e.kick <- struct{}{}:
+	default:
 	}
 }
 
-// discover returns the current view of the world
-func (e *Explorer) discover() (*Discovery, error) {
-	a, err := e.af.Apps()
+// Shutdown stops the main loop after in-flight balancer updates
+// are finished, closes event streams and persists version state
+// of all groups in zookeeper
+func (e *Explorer) Shutdown(ctx context.Context) error {
+	e.stopOnce.Do(func() {
+		close(e.stop)
+		e.events.close()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		e.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+	}
+
+	for _, g := range e.groupList() {
+		err := e.persistState(g)
+		if err != nil {
+			return fmt.Errorf("error persisting state of group %s: %s", g.name, err)
+		}
+	}
+
+	return nil
+}
+
+// discover returns the current view of the world for every group
+// and durations of finder calls, fetching state only once
+// for finders that share the same source
+func (e *Explorer) discover(ctx context.Context) (map[string]*Discovery, map[string]time.Duration, error) {
+	e.mutex.Lock()
+	afs := make(map[string]application.Finder, len(e.groups))
+	bfs := make(map[string]balancer.Finder, len(e.groups))
+	for n, g := range e.groups {
+		afs[n] = g.af
+		bfs[n] = g.bf
+	}
+	e.mutex.Unlock()
+
+	durations := map[string]time.Duration{}
+
+	started := time.Now()
+	a, err := application.FindGroupApps(ctx, afs, func(source string, started time.Time, err error) {
+		e.metrics.observeDiscovery("application", source, started, err)
+	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	b, err := e.bf.Balancers()
+	durations["application"] = time.Since(started)
+
+	started = time.Now()
+	b, err := balancer.FindGroupBalancers(ctx, bfs, func(source string, started time.Time, err error) {
+		e.metrics.observeDiscovery("balancer", source, started, err)
+	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	durations["balancer"] = time.Since(started)
+
+	r := make(map[string]*Discovery, len(afs))
+	for n := range afs {
+		r[n] = &Discovery{
+			Balancers: b[n],
+			Apps:      a[n],
+		}
+	}
+
+	return r, durations, nil
+}
+
+// discoverGroup returns the current view of the world for a single group
+// with durations of finder calls, bypassing the last discovery
+func (e *Explorer) discoverGroup(ctx context.Context, g *group) (*Discovery, map[string]time.Duration, error) {
+	e.mutex.Lock()
+	af, bf := g.af, g.bf
+	e.mutex.Unlock()
+
+	durations := map[string]time.Duration{}
+
+	started := time.Now()
+	a, err := af.Apps(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	durations["application"] = time.Since(started)
+
+	started = time.Now()
+	b, err := bf.Balancers(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	durations["balancer"] = time.Since(started)
+
 	return &Discovery{
 		Balancers: b,
 		Apps:      a,
+	}, durations, nil
+}
+
+// lastDiscovery returns the last discovery of the group made by the main
+// loop or a fresh one if requested or if the main loop has none yet
+func (e *Explorer) lastDiscovery(ctx context.Context, g *group, fresh bool) (DiscoveryResult, error) {
+	if !fresh {
+		e.mutex.Lock()
+		d := e.discoveries[g.name]
+		r := DiscoveryResult{
+			Discovery:  d,
+			Time:       e.discovered,
+			Generation: e.generation,
+			Durations:  durationsInSeconds(e.durations),
+		}
+		e.mutex.Unlock()
+
+		if d != nil {
+			return r, nil
+		}
+	}
+
+	e.mutex.Lock()
+	generation := e.generation
+	e.mutex.Unlock()
+
+	d, durations, err := e.discoverGroup(ctx, g)
+	if err != nil {
+		return DiscoveryResult{}, err
+	}
+
+	return DiscoveryResult{
+		Discovery:  d,
+		Time:       time.Now(),
+		Generation: generation,
+		Durations:  durationsInSeconds(durations),
+		Fresh:      true,
 	}, nil
 }
 
-// updateBalancers updates state of all load balancers
+// updateBalancers updates state of load balancers of all groups
 // in parallel with the specified discovery information
-func (e *Explorer) updateBalancers(discovery *Discovery) {
-	state := e.getState()
-
+func (e *Explorer) updateBalancers(ctx context.Context, discoveries map[string]*Discovery) {
 	now := time.Now()
 
-	updates := []balancer.Balancer{}
-	for _, b := range discovery.Balancers {
-		bs := b.String()
-		if reflect.DeepEqual(e.updated[bs].apps, discovery.Apps) {
-			if now.Sub(e.updated[bs].time) < e.laziness {
-				continue
-			}
-		}
+	e.mutex.Lock()
+	name, laziness := e.name, e.laziness
+	e.mutex.Unlock()
 
-		updates = append(updates, b)
-	}
+	groups := e.groupList()
 
 	wg := sync.WaitGroup{}
-	wg.Add(len(updates))
 
-	for _, b := range updates {
-		go func(b balancer.Balancer) {
-			defer wg.Done()
+	for n, discovery := range discoveries {
+		g, ok := groups[n]
+		if !ok {
+			// group was removed during discovery
+			continue
+		}
 
-			err := b.Update(e.name, discovery.Apps, state)
-			if err != nil {
-				log.Printf("error updating state on %s: %s", b, err)
-				return
-			}
+		state := e.getState(g)
+
+		e.forgetBalancers(g, discovery.Balancers)
+
+		e.mutex.Lock()
+		updated := make(map[string]update, len(g.updated))
+		for bs, u := range g.updated {
+			updated[bs] = u
+		}
+		e.mutex.Unlock()
 
-			e.mutex.Lock()
-			e.updated[b.String()] = update{
-				time: now,
-				apps: discovery.Apps,
+		updates := []balancer.Balancer{}
+		for _, b := range discovery.Balancers {
+			bs := b.String()
+			if reflect.DeepEqual(updated[bs].apps, discovery.Apps) && reflect.DeepEqual(updated[bs].state, state) {
+				if now.Sub(updated[bs].time) < laziness {
+					e.metrics.updateSkips.Add(1, g.name, bs)
+					continue
+				}
 			}
-			e.mutex.Unlock()
-		}(b)
+
+			updates = append(updates, b)
+		}
+
+		wg.Add(len(updates))
+
+		for _, b := range updates {
+			go func(g *group, b balancer.Balancer, discovery *Discovery) {
+				defer wg.Done()
+
+				started := time.Now()
+				err := b.Update(ctx, name, discovery.Apps, state)
+				e.metrics.observeUpdate(g.name, b.String(), started, err)
+				if err != nil {
+					logger.With("group", g.name, "balancer", b).Errorf("error updating state: %s", err)
+
+					e.events.publish(Event{Type: EventBalancerFailed, Group: g.name, Balancer: b.String(), Error: err.Error()})
+
+					e.mutex.Lock()
+					if _, ok := g.failed[b.String()]; !ok {
+						g.failed[b.String()] = now
+					}
+					e.mutex.Unlock()
+
+					return
+				}
+
+				e.mutex.Lock()
+				g.updated[b.String()] = update{
+					time:  now,
+					apps:  discovery.Apps,
+					state: state,
+				}
+				delete(g.failed, b.String())
+				e.pushed = time.Now()
+				e.mutex.Unlock()
+
+				e.events.publish(Event{Type: EventBalancerUpdated, Group: g.name, Balancer: b.String()})
+			}(g, b, discovery)
+		}
 	}
 
 	wg.Wait()
 }
 
-// getState returns the current state of the world
-func (e *Explorer) getState() state.State {
+// forgetBalancers forgets updates, failures and metrics of balancers
+// that are gone, so they don't linger after balancers are replaced
+func (e *Explorer) forgetBalancers(g *group, balancers []balancer.Balancer) {
+	current := make(map[string]bool, len(balancers))
+	for _, b := range balancers {
+		current[b.String()] = true
+	}
+
+	gone := map[string]bool{}
+
 	e.mutex.Lock()
-	s := e.state
+	for b := range g.failed {
+		if !current[b] {
+			delete(g.failed, b)
+			gone[b] = true
+		}
+	}
+
+	for b := range g.updated {
+		if !current[b] {
+			delete(g.updated, b)
+			gone[b] = true
+		}
+	}
 	e.mutex.Unlock()
 
+	for b := range gone {
+		e.metrics.forgetBalancer(g.name, b)
+	}
+}
+
+// getState returns the current state of the world for the group,
+// versions of apps are replaced on change, so they are shared
+func (e *Explorer) getState(g *group) state.State {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	s := state.State{
+		Versions: make(map[string]state.Versions, len(g.state.Versions)),
+	}
+
+	for app, versions := range g.state.Versions {
+		s.Versions[app] = versions
+	}
+
 	return s
 }
 
 // setVersions sets version information for the specified application
-func (e *Explorer) setVersions(app string, versions state.Versions) {
+func (e *Explorer) setVersions(g *group, app string, versions state.Versions) {
 	e.mutex.Lock()
-	e.state.Versions[app] = versions
+	g.state.Versions[app] = versions
 	e.mutex.Unlock()
 }
 
-// persistState persists version state in zookeeper
-func (e *Explorer) persistState() error {
-	s := e.getState()
+// setAllVersions sets version information for several applications at once
+func (e *Explorer) setAllVersions(g *group, all map[string]state.Versions) {
+	e.mutex.Lock()
+	for app, versions := range all {
+		g.state.Versions[app] = versions
+	}
+	e.mutex.Unlock()
+}
+
+// patchVersions merges the patch into versions of the application,
+// versions set to nil in the patch are removed, the result is returned
+func (e *Explorer) patchVersions(g *group, app string, patch map[string]*state.Version) state.Versions {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	versions := state.Versions{}
+	for name, version := range g.state.Versions[app] {
+		versions[name] = version
+	}
+
+	for name, version := range patch {
+		if version == nil {
+			delete(versions, name)
+			continue
+		}
+
+		versions[name] = *version
+	}
+
+	g.state.Versions[app] = versions
+
+	return versions
+}
+
+// deleteVersions removes version information of the application,
+// returning false if there was none
+func (e *Explorer) deleteVersions(g *group, app string) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if _, ok := g.state.Versions[app]; !ok {
+		return false
+	}
+
+	delete(g.state.Versions, app)
+
+	return true
+}
+
+// persistState persists version state of the group in zookeeper
+func (e *Explorer) persistState(g *group) error {
+	s := e.getState(g)
 	b, err := json.Marshal(s)
 	if err != nil {
 		return err
 	}
 
-	_, err = e.zookeeper.Set(e.zp, b, -1)
+	_, err = e.zookeeper.Set(g.zp, b, -1)
 	if err == zk.ErrNoNode {
-		err = e.setUpZkPath(path.Dir(e.zp))
+		err = e.setUpZkPath(path.Dir(g.zp))
 		if err != nil {
 			return err
 		}
 
-		_, err = e.zookeeper.Create(e.zp, b, 0, zk.WorldACL(zk.PermAll))
+		_, err = e.zookeeper.Create(g.zp, b, 0, zk.WorldACL(zk.PermAll))
 	}
 
 	return err
@@ -202,71 +755,378 @@ func (e *Explorer) setUpZkPath(p string) error {
 func (e *Explorer) ServeMux() *http.ServeMux {
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("/_health", func(w http.ResponseWriter, req *http.Request) {
-		w.WriteHeader(http.StatusNoContent)
+	mux.HandleFunc("/_health", e.serveHealth(false))
+	mux.HandleFunc("/_ready", e.serveHealth(true))
+
+	mux.Handle("/metrics", e.metrics.registry)
+	mux.HandleFunc("/log/level", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "GET" {
+			identity, ok := e.authorize(w, req)
+			if !ok {
+				return
+			}
+
+			logger.With("identity", identity).Infof("changing log level")
+		}
+
+		logging.LevelHandler().ServeHTTP(w, req)
+	})
+	mux.HandleFunc("/events", e.serveEvents)
+	mux.HandleFunc("/ui/", serveUI)
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/" {
+			http.NotFound(w, req)
+			return
+		}
+
+		http.Redirect(w, req, "/ui/", http.StatusFound)
 	})
 
-	mux.HandleFunc("/state", func(w http.ResponseWriter, req *http.Request) {
+	mux.HandleFunc("/groups", func(w http.ResponseWriter, req *http.Request) {
 		if req.Method != "GET" {
 			http.Error(w, "expected GET", http.StatusBadRequest)
 			return
 		}
 
+		groups := []string{}
+		for n := range e.groupList() {
+			groups = append(groups, n)
+		}
+
+		sort.Strings(groups)
+
 		w.Header().Add("Content-type", "application/json")
-		err := json.NewEncoder(w).Encode(e.getState())
+		err := json.NewEncoder(w).Encode(groups)
 		if err != nil {
-			log.Println("error sending state:", err)
+			logger.Warnf("error sending groups: %s", err)
 		}
 	})
 
-	mux.HandleFunc("/versions/", func(w http.ResponseWriter, req *http.Request) {
-		if req.Method != "POST" && req.Method != "PUT" {
-			http.Error(w, "expected POST or PUT", http.StatusBadRequest)
+	mux.HandleFunc("/groups/", func(w http.ResponseWriter, req *http.Request) {
+		p := strings.SplitN(strings.TrimPrefix(req.URL.Path, "/groups/"), "/", 2)
+		if len(p) != 2 {
+			http.NotFound(w, req)
 			return
 		}
 
-		d := json.NewDecoder(req.Body)
-		v := state.Versions{}
-		err := d.Decode(&v)
+		g := e.lookupGroup(p[0])
+		if g == nil {
+			http.Error(w, fmt.Sprintf("unknown balancer group %q", p[0]), http.StatusNotFound)
+			return
+		}
+
+		e.serveGroup(w, req, g, p[1])
+	})
+
+	// the only group is also available without prefix
+	for _, p := range []string{"/state", "/versions", "/versions/", "/discovery", "/discovery/", "/orphans", "/balancers", "/sources"} {
+		mux.HandleFunc(p, func(w http.ResponseWriter, req *http.Request) {
+			g := e.lookupGroup("")
+			if g == nil {
+				http.Error(w, "several balancer groups are managed, use /groups/{group} prefix", http.StatusNotFound)
+				return
+			}
+
+			e.serveGroup(w, req, g, strings.TrimPrefix(req.URL.Path, "/"))
+		})
+	}
+
+	return mux
+}
+
+// serveGroup serves api request for the group, p is the path inside of the group
+func (e *Explorer) serveGroup(w http.ResponseWriter, req *http.Request, g *group, p string) {
+	switch {
+	case p == "state":
+		e.serveState(w, req, g)
+	case p == "balancers":
+		e.serveBalancers(w, req, g)
+	case p == "sources":
+		e.serveSources(w, req, g)
+	case p == "orphans":
+		e.serveOrphans(w, req, g)
+	case p == "versions":
+		e.serveAllVersions(w, req, g)
+	case strings.HasPrefix(p, "versions/"):
+		e.serveVersions(w, req, g, strings.TrimPrefix(p, "versions/"))
+	case p == "discovery":
+		e.serveDiscovery(w, req, g)
+	case strings.HasPrefix(p, "discovery/"):
+		e.serveAppDiscovery(w, req, g, strings.TrimPrefix(p, "discovery/"))
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// serveState serves version state of the group
+func (e *Explorer) serveState(w http.ResponseWriter, req *http.Request, g *group) {
+	if req.Method != "GET" {
+		http.Error(w, "expected GET", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Add("Content-type", "application/json")
+	err := json.NewEncoder(w).Encode(e.getState(g))
+	if err != nil {
+		logger.With("group", g.name).Warnf("error sending state: %s", err)
+	}
+}
+
+// serveAllVersions serves versions of all apps in the group on GET
+// and atomically sets versions of several apps on POST, asking
+// the main loop to push the new state to balancers right away
+func (e *Explorer) serveAllVersions(w http.ResponseWriter, req *http.Request, g *group) {
+	if req.Method == "GET" {
+		w.Header().Add("Content-type", "application/json")
+		err := json.NewEncoder(w).Encode(e.getState(g).Versions)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("version decoding failed: %s", err), http.StatusBadRequest)
+			logger.With("group", g.name).Warnf("error sending versions: %s", err)
+		}
+
+		return
+	}
+
+	if req.Method != "POST" {
+		http.Error(w, "expected GET or POST", http.StatusBadRequest)
+		return
+	}
+
+	all := map[string]state.Versions{}
+	err := json.NewDecoder(req.Body).Decode(&all)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("version decoding failed: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	apps := make([]string, 0, len(all))
+	for app, versions := range all {
+		err = validateVersions(app, versions)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		a := strings.TrimPrefix(req.URL.Path, "/versions/")
-		if a == "" {
-			http.Error(w, "application is not specified", http.StatusBadRequest)
+		apps = append(apps, app)
+	}
+
+	if len(apps) == 0 {
+		http.Error(w, "no applications specified", http.StatusBadRequest)
+		return
+	}
+
+	sort.Strings(apps)
+
+	identity, ok := e.authorize(w, req, apps...)
+	if !ok {
+		return
+	}
+
+	e.setAllVersions(g, all)
+
+	for _, app := range apps {
+		e.events.publish(Event{Type: EventVersionsChanged, Group: g.name, App: app, Versions: all[app], Identity: identity})
+	}
+
+	logger.With("group", g.name, "apps", strings.Join(apps, ","), "identity", identity).Infof("versions set: %v", all)
+
+	err = e.persistState(g)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("state set successfully, but persisting failed: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	e.triggerCycle()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateVersions checks that versions of the app can be set
+func validateVersions(app string, versions state.Versions) error {
+	if app == "" || strings.Contains(app, "/") {
+		return fmt.Errorf("invalid application name %q", app)
+	}
+
+	for name, version := range versions {
+		if name == "" {
+			return fmt.Errorf("empty version name for application %q", app)
+		}
+
+		if version.Weight < 0 {
+			return fmt.Errorf("negative weight of version %q for application %q", name, app)
+		}
+	}
+
+	return nil
+}
+
+// serveVersions serves and changes versions of the app in the group
+func (e *Explorer) serveVersions(w http.ResponseWriter, req *http.Request, g *group, a string) {
+	if a == "" {
+		http.Error(w, "application is not specified", http.StatusBadRequest)
+		return
+	}
+
+	if req.Method == "GET" {
+		versions, ok := e.getState(g).Versions[a]
+		if !ok {
+			http.Error(w, fmt.Sprintf("versions of app %q are not set", a), http.StatusNotFound)
 			return
 		}
 
-		e.setVersions(a, v)
-		err = e.persistState()
+		w.Header().Add("Content-type", "application/json")
+		err := json.NewEncoder(w).Encode(versions)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("state set successfully, but persisting failed: %s", err), http.StatusInternalServerError)
+			logger.With("group", g.name, "app", a).Warnf("error sending versions: %s", err)
+		}
+
+		return
+	}
+
+	if req.Method != "POST" && req.Method != "PUT" && req.Method != "PATCH" && req.Method != "DELETE" {
+		http.Error(w, "expected GET, POST, PUT, PATCH or DELETE", http.StatusBadRequest)
+		return
+	}
+
+	identity, ok := e.authorize(w, req, a)
+	if !ok {
+		return
+	}
+
+	event := Event{Type: EventVersionsChanged, Group: g.name, App: a, Identity: identity}
+
+	switch req.Method {
+	case "DELETE":
+		if !e.deleteVersions(g, a) {
+			http.Error(w, fmt.Sprintf("versions of app %q are not set", a), http.StatusNotFound)
 			return
 		}
 
-		w.WriteHeader(http.StatusNoContent)
-	})
+		event.Type = EventVersionsDeleted
 
-	mux.HandleFunc("/discovery", func(w http.ResponseWriter, req *http.Request) {
-		d, err := e.discover()
+		logger.With("group", g.name, "app", a, "identity", identity).Infof("versions deleted")
+	case "PATCH":
+		patch := map[string]*state.Version{}
+		err := json.NewDecoder(req.Body).Decode(&patch)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("error getting servers: %s", err), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("version decoding failed: %s", err), http.StatusBadRequest)
 			return
 		}
 
-		w.Header().Add("Content-type", "application/json")
-		err = json.NewEncoder(w).Encode(d)
+		set := state.Versions{}
+		for name, version := range patch {
+			if version != nil {
+				set[name] = *version
+			}
+		}
+
+		err = validateVersions(a, set)
 		if err != nil {
-			log.Println("error sending discovery:", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
-	})
 
-	return mux
+		event.Versions = e.patchVersions(g, a, patch)
+
+		logger.With("group", g.name, "app", a, "identity", identity).Infof("versions patched: %v", event.Versions)
+	default:
+		v := state.Versions{}
+		err := json.NewDecoder(req.Body).Decode(&v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("version decoding failed: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		err = validateVersions(a, v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		e.setVersions(g, a, v)
+		event.Versions = v
+
+		logger.With("group", g.name, "app", a, "identity", identity).Infof("versions set: %v", v)
+	}
+
+	e.events.publish(event)
+
+	err := e.persistState(g)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("state set successfully, but persisting failed: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	e.triggerCycle()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveDiscovery serves the last view of the world for the group,
+// ?fresh=true fetches the current one bypassing the last discovery
+func (e *Explorer) serveDiscovery(w http.ResponseWriter, req *http.Request, g *group) {
+	if req.Method != "GET" {
+		http.Error(w, "expected GET", http.StatusBadRequest)
+		return
+	}
+
+	r, err := e.lastDiscovery(req.Context(), g, req.URL.Query().Get("fresh") == "true")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error getting servers: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	versions := e.getState(g).Versions
+
+	r.Weights = make(map[string]map[string]float64, len(r.Apps))
+	for n, app := range r.Apps {
+		r.Weights[n] = effectiveWeights(app, versions[n])
+	}
+
+	w.Header().Add("Content-type", "application/json")
+	err = json.NewEncoder(w).Encode(r)
+	if err != nil {
+		logger.With("group", g.name).Warnf("error sending discovery: %s", err)
+	}
+}
+
+// serveAppDiscovery serves servers, versions and effective
+// weights of versions of a single app in the group
+func (e *Explorer) serveAppDiscovery(w http.ResponseWriter, req *http.Request, g *group, a string) {
+	if req.Method != "GET" {
+		http.Error(w, "expected GET", http.StatusBadRequest)
+		return
+	}
+
+	r, err := e.lastDiscovery(req.Context(), g, req.URL.Query().Get("fresh") == "true")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error getting servers: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	app, ok := r.Apps[a]
+	if !ok {
+		http.Error(w, fmt.Sprintf("app %q is not discovered", a), http.StatusNotFound)
+		return
+	}
+
+	versions := e.getState(g).Versions[a]
+
+	w.Header().Add("Content-type", "application/json")
+	err = json.NewEncoder(w).Encode(AppDiscovery{
+		App:        app,
+		Versions:   versions,
+		Weights:    effectiveWeights(app, versions),
+		Time:       r.Time,
+		Generation: r.Generation,
+		Fresh:      r.Fresh,
+	})
+	if err != nil {
+		logger.With("group", g.name, "app", a).Warnf("error sending discovery: %s", err)
+	}
 }
 
 type update struct {
-	time time.Time
-	apps application.Apps
+	time  time.Time
+	apps  application.Apps
+	state state.State
 }
@@ -0,0 +1,315 @@
+package zoidberg
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bobrik/zoidberg/application"
+	"github.com/bobrik/zoidberg/balancer"
+	"github.com/bobrik/zoidberg/state"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+func TestPatchVersions(t *testing.T) {
+	table := []struct {
+		versions state.Versions
+		patch    map[string]*state.Version
+		result   state.Versions
+	}{
+		{
+			versions: nil,
+			patch:    map[string]*state.Version{"v1": {Weight: 1}},
+			result:   state.Versions{"v1": {Weight: 1}},
+		},
+		{
+			versions: state.Versions{"v1": {Weight: 1}},
+			patch:    map[string]*state.Version{"v2": {Weight: 10}},
+			result:   state.Versions{"v1": {Weight: 1}, "v2": {Weight: 10}},
+		},
+		{
+			versions: state.Versions{"v1": {Weight: 1}, "v2": {Weight: 10}},
+			patch:    map[string]*state.Version{"v1": nil, "v2": {Weight: 5}},
+			result:   state.Versions{"v2": {Weight: 5}},
+		},
+	}
+
+	for i, row := range table {
+		e := &Explorer{}
+		g := &group{state: state.State{Versions: map[string]state.Versions{}}}
+		if row.versions != nil {
+			g.state.Versions["app"] = row.versions
+		}
+
+		before := e.getState(g)
+
+		result := e.patchVersions(g, "app", row.patch)
+		if !reflect.DeepEqual(result, row.result) {
+			t.Errorf("row %d: expected: %v, got: %v", i, row.result, result)
+		}
+
+		if !reflect.DeepEqual(before.Versions["app"], row.versions) {
+			t.Errorf("row %d: expected previous state to stay intact: %v, got: %v", i, row.versions, before.Versions["app"])
+		}
+	}
+}
+
+func TestValidateVersions(t *testing.T) {
+	table := []struct {
+		app      string
+		versions state.Versions
+		valid    bool
+	}{
+		{app: "app", versions: state.Versions{"1": {Weight: 1}, "2": {Weight: 0}}, valid: true},
+		{app: "app", versions: state.Versions{}, valid: true},
+		{app: "", versions: state.Versions{"1": {Weight: 1}}, valid: false},
+		{app: "a/b", versions: state.Versions{"1": {Weight: 1}}, valid: false},
+		{app: "app", versions: state.Versions{"": {Weight: 1}}, valid: false},
+		{app: "app", versions: state.Versions{"1": {Weight: -1}}, valid: false},
+	}
+
+	for _, row := range table {
+		err := validateVersions(row.app, row.versions)
+		if (err == nil) != row.valid {
+			t.Errorf("%q %v: expected valid: %v, got: %v", row.app, row.versions, row.valid, err)
+		}
+	}
+}
+
+func TestServeVersionsValidation(t *testing.T) {
+	table := []struct {
+		method string
+		body   string
+	}{
+		{method: "PUT", body: `{"1":{"weight":-1}}`},
+		{method: "PUT", body: `{"":{"weight":1}}`},
+		{method: "PATCH", body: `{"2":{"weight":-1}}`},
+		{method: "PATCH", body: `{"1":null,"":{"weight":1}}`},
+	}
+
+	for _, row := range table {
+		e := &Explorer{}
+		g := &group{state: state.State{Versions: map[string]state.Versions{"app": {"1": {Weight: 1}}}}}
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(row.method, "/versions/app", strings.NewReader(row.body))
+
+		e.serveVersions(w, req, g, "app")
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: expected status %d, got %d", row.method, row.body, http.StatusBadRequest, w.Code)
+		}
+
+		expected := state.Versions{"1": {Weight: 1}}
+		if !reflect.DeepEqual(e.getState(g).Versions["app"], expected) {
+			t.Errorf("%s %s: versions changed to %v", row.method, row.body, e.getState(g).Versions["app"])
+		}
+	}
+}
+
+// fakeStateStore keeps znodes in memory
+type fakeStateStore map[string][]byte
+
+func (f fakeStateStore) Get(path string) ([]byte, *zk.Stat, error) {
+	b, ok := f[path]
+	if !ok {
+		return nil, nil, zk.ErrNoNode
+	}
+
+	return b, &zk.Stat{}, nil
+}
+
+func (f fakeStateStore) Multi(ops ...interface{}) ([]zk.MultiResponse, error) {
+	for _, op := range ops {
+		switch r := op.(type) {
+		case *zk.CreateRequest:
+			f[r.Path] = r.Data
+		case *zk.SetDataRequest:
+			f[r.Path] = r.Data
+		}
+	}
+
+	return nil, nil
+}
+
+func TestMigrateStateOneToTwoGroups(t *testing.T) {
+	zs := fakeStateStore{
+		"/zoidberg": []byte(`{"versions":{"app":{"v1":{"weight":3}}}}`),
+	}
+
+	// single group used to keep its state at the root, the second group
+	// is added later and both keep their state under their own names
+	groups := []struct {
+		zp     string
+		legacy string
+		state  state.State
+	}{
+		{
+			zp:     "/zoidberg/a",
+			legacy: "/zoidberg",
+			state:  state.State{Versions: map[string]state.Versions{"app": {"v1": {Weight: 3}}}},
+		},
+		{
+			zp:    "/zoidberg/b",
+			state: state.State{Versions: map[string]state.Versions{}},
+		},
+	}
+
+	for round := 0; round < 2; round++ {
+		for _, g := range groups {
+			if g.legacy != "" {
+				moved, err := migrateState(zs, g.zp, g.legacy)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if moved != (round == 0) {
+					t.Errorf("round %d: group %s: unexpected moved: %v", round, g.zp, moved)
+				}
+			}
+
+			s, err := loadState(zs, g.zp)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(s, g.state) {
+				t.Errorf("round %d: group %s: expected state %v, got %v", round, g.zp, g.state, s)
+			}
+		}
+
+		if len(zs["/zoidberg"]) != 0 {
+			t.Errorf("round %d: expected empty root state, got %q", round, zs["/zoidberg"])
+		}
+	}
+}
+
+// closingFinder is a finder that records whether it was closed
+type closingFinder struct {
+	closed bool
+}
+
+func (f *closingFinder) Apps(ctx context.Context) (application.Apps, error) {
+	return application.Apps{}, nil
+}
+
+func (f *closingFinder) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestReconfigureClosesReplacedFinders(t *testing.T) {
+	replaced := &closingFinder{}
+	kept := &closingFinder{}
+	current := &closingFinder{}
+
+	e := &Explorer{
+		groups: map[string]*group{
+			"a": {name: "a", zp: "/zoidberg/a", af: replaced},
+			"b": {name: "b", zp: "/zoidberg/b", af: kept},
+		},
+	}
+
+	err := e.Reconfigure("test", []Group{
+		{Name: "a", ZookeeperPath: "/zoidberg/a", ApplicationFinder: current},
+		{Name: "b", ZookeeperPath: "/zoidberg/b", ApplicationFinder: kept},
+	}, time.Second, time.Second)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !replaced.closed {
+		t.Errorf("expected replaced finder to be closed")
+	}
+
+	if kept.closed || current.closed {
+		t.Errorf("expected finders in use to stay open")
+	}
+}
+
+func TestForgetBalancersMetrics(t *testing.T) {
+	e := &Explorer{}
+	e.metrics = newExplorerMetrics(e)
+
+	g := &group{
+		name: "lb",
+		updated: map[string]update{
+			"10.0.0.1:80": {},
+			"10.0.0.2:80": {},
+		},
+		failed: map[string]time.Time{
+			"10.0.0.3:80": time.Now(),
+		},
+	}
+
+	for _, b := range []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.3:80"} {
+		e.metrics.observeUpdate(g.name, b, time.Now(), nil)
+		e.metrics.updateSkips.Add(1, g.name, b)
+	}
+
+	e.forgetBalancers(g, []balancer.Balancer{{Host: "10.0.0.1", Port: 80}})
+
+	if len(g.updated) != 1 || len(g.failed) != 0 {
+		t.Errorf("expected only the current balancer to be kept, got: %v and %v", g.updated, g.failed)
+	}
+
+	w := httptest.NewRecorder()
+	e.metrics.registry.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	if !strings.Contains(w.Body.String(), `balancer="10.0.0.1:80"`) {
+		t.Errorf("expected metrics of the current balancer, got:\n%s", w.Body.String())
+	}
+
+	for _, b := range []string{"10.0.0.2:80", "10.0.0.3:80"} {
+		if strings.Contains(w.Body.String(), `balancer="`+b+`"`) {
+			t.Errorf("expected metrics of %s to be removed, got:\n%s", b, w.Body.String())
+		}
+	}
+}
+
+func TestServeDiscoveryWeights(t *testing.T) {
+	g := &group{
+		name:  "lb",
+		state: state.State{Versions: map[string]state.Versions{"app": {"1": {Weight: 1}, "2": {Weight: 3}}}},
+	}
+
+	e := &Explorer{
+		discoveries: map[string]*Discovery{
+			"lb": {
+				Apps: application.Apps{
+					"app": {
+						Name:    "app",
+						Servers: []application.Server{{Host: "a", Port: 1, Version: "1"}, {Host: "b", Port: 1, Version: "2"}},
+					},
+					"other": {
+						Name:    "other",
+						Servers: []application.Server{{Host: "c", Port: 1, Version: "1"}},
+					},
+				},
+			},
+		},
+	}
+
+	w := httptest.NewRecorder()
+	e.serveDiscovery(w, httptest.NewRequest("GET", "/discovery", nil), g)
+
+	r := DiscoveryResult{}
+	if err := json.NewDecoder(w.Body).Decode(&r); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]map[string]float64{
+		"app":   {"1": 0.25, "2": 0.75},
+		"other": {"1": 1},
+	}
+
+	if !reflect.DeepEqual(r.Weights, expected) {
+		t.Errorf("expected weights: %v, got: %v", expected, r.Weights)
+	}
+}
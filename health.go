@@ -0,0 +1,224 @@
+package zoidberg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// HealthThresholds defines when explorer is considered unhealthy,
+// zero durations and negative counts disable the corresponding check
+type HealthThresholds struct {
+	// DiscoveryAge is the maximum time since the last successful discovery
+	DiscoveryAge time.Duration
+	// UpdateAge is the maximum time since the last successful balancer update
+	UpdateAge time.Duration
+	// FailingBalancers is the maximum number of balancers failing updates
+	FailingBalancers int
+	// ZookeeperSession is the maximum time without zookeeper session
+	ZookeeperSession time.Duration
+}
+
+// Health is a report of explorer's health checks
+type Health struct {
+	Ready   bool                   `json:"ready"`
+	Healthy bool                   `json:"healthy"`
+	Checks  map[string]HealthCheck `json:"checks"`
+}
+
+// HealthCheck is a result of a single health check
+type HealthCheck struct {
+	OK        bool   `json:"ok"`
+	Value     string `json:"value"`
+	Threshold string `json:"threshold,omitempty"`
+	// Liveness checks fail health, other checks only fail readiness
+	Liveness bool `json:"liveness"`
+}
+
+// SetHealthThresholds sets thresholds for health checks
+func (e *Explorer) SetHealthThresholds(t HealthThresholds) {
+	e.mutex.Lock()
+	e.thresholds = t
+	e.mutex.Unlock()
+}
+
+// Health returns the current health report of explorer,
+// explorer is ready when all checks pass and healthy when
+// liveness checks pass, restarting doesn't help other checks
+func (e *Explorer) Health() Health {
+	now := time.Now()
+
+	e.mutex.Lock()
+	t := e.thresholds
+	discovered, pushed, started, session := e.discovered, e.pushed, e.started, e.session
+	failing := 0
+	for _, g := range e.groups {
+		failing += len(g.failed)
+	}
+	e.mutex.Unlock()
+
+	h := Health{
+		Ready:   true,
+		Healthy: true,
+		Checks:  map[string]HealthCheck{},
+	}
+
+	add := func(name string, c HealthCheck) {
+		h.Checks[name] = c
+		if !c.OK {
+			h.Ready = false
+			if c.Liveness {
+				h.Healthy = false
+			}
+		}
+	}
+
+	add("zookeeper", sessionCheck(now, session, t.ZookeeperSession))
+
+	add("discovery", ageCheck(now, discovered, started, t.DiscoveryAge))
+	add("balancer_update", ageCheck(now, pushed, started, t.UpdateAge))
+
+	fc := HealthCheck{
+		OK:    t.FailingBalancers < 0 || failing <= t.FailingBalancers,
+		Value: strconv.Itoa(failing),
+	}
+	if t.FailingBalancers >= 0 {
+		fc.Threshold = strconv.Itoa(t.FailingBalancers)
+	}
+	add("failing_balancers", fc)
+
+	return h
+}
+
+// ageCheck checks that the last event happened recently enough
+func ageCheck(now, last, started time.Time, threshold time.Duration) HealthCheck {
+	c := HealthCheck{
+		OK:       true,
+		Value:    "never",
+		Liveness: true,
+	}
+
+	if !last.IsZero() {
+		c.Value = now.Sub(last).String()
+	}
+
+	if threshold <= 0 {
+		return c
+	}
+
+	c.Threshold = threshold.String()
+
+	if last.IsZero() {
+		// not ready until the first event, but only unhealthy
+		// if it does not happen within threshold after start
+		c.OK = false
+		c.Liveness = now.Sub(started) > threshold
+		return c
+	}
+
+	c.OK = now.Sub(last) <= threshold
+
+	return c
+}
+
+// sessionCheck checks that zookeeper session is established, readiness
+// fails right away and health fails if session is lost for too long,
+// the last lost session is reported even if it is already restored
+func sessionCheck(now time.Time, s zkSession, threshold time.Duration) HealthCheck {
+	c := HealthCheck{
+		OK:    s.state == zk.StateHasSession,
+		Value: s.state.String(),
+	}
+
+	if !s.restored.IsZero() {
+		c.Value = fmt.Sprintf("%s, last lost for %s %s ago", c.Value, s.restored.Sub(s.lastLost), now.Sub(s.restored))
+	}
+
+	if threshold <= 0 {
+		return c
+	}
+
+	c.Threshold = threshold.String()
+	c.Liveness = !c.OK && now.Sub(s.lost) > threshold
+
+	return c
+}
+
+// zkSession is the state of zookeeper session as seen in session events
+type zkSession struct {
+	state zk.State
+	// established is set once the first session is established
+	established bool
+	// lost is when the session was lost, zero while it is established
+	lost time.Time
+	// lastLost and restored are when the last lost session was lost and restored
+	lastLost time.Time
+	restored time.Time
+}
+
+// observe records the state from a session event that happened at now
+func (s *zkSession) observe(state zk.State, now time.Time) {
+	if state == zk.StateHasSession {
+		if s.established && !s.lost.IsZero() {
+			s.lastLost, s.restored = s.lost, now
+		}
+
+		s.established = true
+		s.lost = time.Time{}
+	} else if s.lost.IsZero() {
+		s.lost = now
+	}
+
+	s.state = state
+}
+
+// ObserveZookeeperEvent records changes of zookeeper session from events
+// of the connection, so sessions lost between health checks are noticed
+func (e *Explorer) ObserveZookeeperEvent(ev zk.Event) {
+	if ev.Type != zk.EventSession {
+		return
+	}
+
+	e.mutex.Lock()
+	e.session.observe(ev.State, time.Now())
+	e.mutex.Unlock()
+}
+
+// serveHealth serves health or readiness check, verbose
+// query parameter adds a json report of all checks
+func (e *Explorer) serveHealth(ready bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		h := e.Health()
+
+		ok := h.Healthy
+		if ready {
+			ok = h.Ready
+		}
+
+		status := http.StatusNoContent
+		if !ok {
+			status = http.StatusServiceUnavailable
+		}
+
+		if _, verbose := req.URL.Query()["verbose"]; !verbose {
+			w.WriteHeader(status)
+			return
+		}
+
+		if ok {
+			status = http.StatusOK
+		}
+
+		w.Header().Add("Content-type", "application/json")
+		w.WriteHeader(status)
+
+		err := json.NewEncoder(w).Encode(h)
+		if err != nil {
+			logger.Warnf("error sending health: %s", err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package zoidberg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+func TestAgeCheck(t *testing.T) {
+	now := time.Now()
+
+	table := []struct {
+		last      time.Time
+		started   time.Time
+		threshold time.Duration
+		ok        bool
+		liveness  bool
+	}{
+		{
+			last:      time.Time{},
+			started:   now.Add(-time.Hour),
+			threshold: 0,
+			ok:        true,
+			liveness:  true,
+		},
+		{
+			last:      now.Add(-time.Second),
+			started:   now.Add(-time.Hour),
+			threshold: time.Minute,
+			ok:        true,
+			liveness:  true,
+		},
+		{
+			last:      now.Add(-time.Hour),
+			started:   now.Add(-time.Hour),
+			threshold: time.Minute,
+			ok:        false,
+			liveness:  true,
+		},
+		{
+			last:      time.Time{},
+			started:   now.Add(-time.Second),
+			threshold: time.Minute,
+			ok:        false,
+			liveness:  false,
+		},
+		{
+			last:      time.Time{},
+			started:   now.Add(-time.Hour),
+			threshold: time.Minute,
+			ok:        false,
+			liveness:  true,
+		},
+	}
+
+	for i, row := range table {
+		c := ageCheck(now, row.last, row.started, row.threshold)
+		if c.OK != row.ok || c.Liveness != row.liveness {
+			t.Errorf("row %d: expected ok=%v liveness=%v, got: %+v", i, row.ok, row.liveness, c)
+		}
+	}
+}
+
+func TestSessionCheck(t *testing.T) {
+	now := time.Now()
+
+	table := []struct {
+		session   zkSession
+		threshold time.Duration
+		ok        bool
+		liveness  bool
+		value     string
+	}{
+		{
+			session:   zkSession{state: zk.StateHasSession},
+			threshold: time.Minute,
+			ok:        true,
+			liveness:  false,
+			value:     "StateHasSession",
+		},
+		{
+			session:   zkSession{state: zk.StateDisconnected, lost: now.Add(-time.Hour)},
+			threshold: 0,
+			ok:        false,
+			liveness:  false,
+			value:     "StateDisconnected",
+		},
+		{
+			session:   zkSession{state: zk.StateDisconnected, lost: now.Add(-time.Second)},
+			threshold: time.Minute,
+			ok:        false,
+			liveness:  false,
+			value:     "StateDisconnected",
+		},
+		{
+			session:   zkSession{state: zk.StateExpired, lost: now.Add(-time.Hour)},
+			threshold: time.Minute,
+			ok:        false,
+			liveness:  true,
+			value:     "StateExpired",
+		},
+		{
+			session: zkSession{
+				state:    zk.StateHasSession,
+				lastLost: now.Add(-time.Minute),
+				restored: now.Add(-time.Minute + time.Second*3),
+			},
+			threshold: time.Minute,
+			ok:        true,
+			liveness:  false,
+			value:     "StateHasSession, last lost for 3s 57s ago",
+		},
+	}
+
+	for i, row := range table {
+		c := sessionCheck(now, row.session, row.threshold)
+		if c.OK != row.ok || c.Liveness != row.liveness || c.Value != row.value {
+			t.Errorf("row %d: expected ok=%v liveness=%v value=%q, got: %+v", i, row.ok, row.liveness, row.value, c)
+		}
+	}
+}
+
+func TestSessionObserve(t *testing.T) {
+	start := time.Now()
+
+	s := zkSession{state: zk.StateUnknown, lost: start}
+
+	// initial connection is not a lost session
+	s.observe(zk.StateConnecting, start.Add(time.Second))
+	s.observe(zk.StateHasSession, start.Add(time.Second*2))
+
+	if !s.lost.IsZero() || !s.restored.IsZero() {
+		t.Fatalf("expected established session without losses, got: %+v", s)
+	}
+
+	// session dropped and came back between health checks
+	s.observe(zk.StateDisconnected, start.Add(time.Minute))
+	s.observe(zk.StateConnecting, start.Add(time.Minute+time.Second))
+	s.observe(zk.StateHasSession, start.Add(time.Minute+time.Second*5))
+
+	if s.state != zk.StateHasSession || !s.lost.IsZero() {
+		t.Fatalf("expected established session, got: %+v", s)
+	}
+
+	if !s.lastLost.Equal(start.Add(time.Minute)) || !s.restored.Equal(start.Add(time.Minute+time.Second*5)) {
+		t.Errorf("expected session lost at the first disconnect and restored after, got: %+v", s)
+	}
+
+	// the loss time is the first event without session
+	s.observe(zk.StateDisconnected, start.Add(time.Hour))
+	s.observe(zk.StateExpired, start.Add(time.Hour+time.Minute))
+
+	if s.state != zk.StateExpired || !s.lost.Equal(start.Add(time.Hour)) {
+		t.Errorf("expected session lost since the first disconnect, got: %+v", s)
+	}
+}
+
+func TestObserveZookeeperEvent(t *testing.T) {
+	e := &Explorer{session: zkSession{state: zk.StateUnknown, lost: time.Now()}}
+
+	e.ObserveZookeeperEvent(zk.Event{Type: zk.EventSession, State: zk.StateHasSession})
+	e.ObserveZookeeperEvent(zk.Event{Type: zk.EventNodeDataChanged, State: zk.StateDisconnected})
+
+	c := e.Health().Checks["zookeeper"]
+	if !c.OK || c.Value != "StateHasSession" {
+		t.Errorf("expected zookeeper check to pass ignoring non-session events, got: %+v", c)
+	}
+}
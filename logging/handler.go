@@ -0,0 +1,39 @@
+package logging
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// LevelHandler serves the current log level on GET
+// and changes it to the level from request body on PUT
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case "GET":
+			fmt.Fprintln(w, GetLevel())
+		case "PUT", "POST":
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("error reading level: %s", err), http.StatusBadRequest)
+				return
+			}
+
+			l, err := ParseLevel(strings.TrimSpace(string(b)))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			SetLevel(l)
+
+			Component("logging").Infof("log level set to %s", l)
+
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "expected GET, PUT or POST", http.StatusBadRequest)
+		}
+	})
+}
@@ -0,0 +1,345 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is a severity of log records
+type Level int
+
+const (
+	// Debug is for records useful for troubleshooting
+	Debug Level = iota
+	// Info is for records about normal operation
+	Info
+	// Warn is for records about misconfiguration and recoverable issues
+	Warn
+	// Error is for records about failed operations
+	Error
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+// String returns the name of the level
+func (l Level) String() string {
+	if l < Debug || l > Error {
+		return strconv.Itoa(int(l))
+	}
+
+	return levelNames[l]
+}
+
+// ParseLevel returns level by its name
+func ParseLevel(s string) (Level, error) {
+	for i, n := range levelNames {
+		if strings.EqualFold(s, n) {
+			return Level(i), nil
+		}
+	}
+
+	return Info, fmt.Errorf("unknown log level %q, expected one of: %s", s, strings.Join(levelNames, ", "))
+}
+
+// Format is a format of log records
+type Format string
+
+const (
+	// Logfmt writes records as key=value pairs
+	Logfmt Format = "logfmt"
+	// JSON writes records as json objects
+	JSON Format = "json"
+)
+
+// ParseFormat returns format by its name
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case Logfmt, JSON:
+		return f, nil
+	default:
+		return Logfmt, fmt.Errorf("unknown log format %q, expected logfmt or json", s)
+	}
+}
+
+// output is shared by all loggers and writes records
+type output struct {
+	w       io.Writer
+	format  Format
+	level   Level
+	window  time.Duration
+	seen    map[string]*seen
+	swept   time.Time
+	mutex   sync.Mutex
+	nowFunc func() time.Time
+}
+
+// seen tracks repeated records
+type seen struct {
+	last       time.Time
+	suppressed int
+	level      Level
+	msg        string
+	fields     []field
+}
+
+var root = &output{
+	w:       os.Stderr,
+	format:  Logfmt,
+	level:   Info,
+	window:  time.Minute,
+	seen:    map[string]*seen{},
+	nowFunc: time.Now,
+}
+
+// SetOutput sets destination of log records
+func SetOutput(w io.Writer) {
+	root.mutex.Lock()
+	root.w = w
+	root.mutex.Unlock()
+}
+
+// SetFormat sets format of log records
+func SetFormat(f Format) {
+	root.mutex.Lock()
+	root.format = f
+	root.mutex.Unlock()
+}
+
+// SetLevel sets minimum level of records that are written
+func SetLevel(l Level) {
+	root.mutex.Lock()
+	root.level = l
+	root.mutex.Unlock()
+}
+
+// GetLevel returns minimum level of records that are written
+func GetLevel() Level {
+	root.mutex.Lock()
+	defer root.mutex.Unlock()
+
+	return root.level
+}
+
+// SetDedupWindow sets the time during which identical records are
+// suppressed after the first one, zero disables deduplication,
+// error records are never suppressed
+func SetDedupWindow(d time.Duration) {
+	root.mutex.Lock()
+	root.window = d
+	root.mutex.Unlock()
+}
+
+// Logger writes log records with a set of fields
+type Logger struct {
+	out    *output
+	fields []field
+}
+
+// field is a single key value pair attached to log records
+type field struct {
+	key   string
+	value string
+}
+
+// Component returns a logger for the named component
+func Component(name string) *Logger {
+	return (&Logger{out: root}).With("component", name)
+}
+
+// With returns a logger with additional fields from key value pairs
+func (l *Logger) With(kv ...interface{}) *Logger {
+	fields := make([]field, len(l.fields), len(l.fields)+len(kv)/2)
+	copy(fields, l.fields)
+
+	for i := 0; i+1 < len(kv); i += 2 {
+		fields = append(fields, field{
+			key:   fmt.Sprint(kv[i]),
+			value: fmt.Sprint(kv[i+1]),
+		})
+	}
+
+	return &Logger{out: l.out, fields: fields}
+}
+
+// Debugf writes a record with debug level
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.log(Debug, format, args...)
+}
+
+// Infof writes a record with info level
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.log(Info, format, args...)
+}
+
+// Warnf writes a record with warn level
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.log(Warn, format, args...)
+}
+
+// Errorf writes a record with error level
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.log(Error, format, args...)
+}
+
+// Fatalf writes a record with error level and exits
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.log(Error, format, args...)
+	os.Exit(1)
+}
+
+// log writes a record unless it is below minimum level
+// or an identical non-error record was written recently
+func (l *Logger) log(level Level, format string, args ...interface{}) {
+	o := l.out
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if level < o.level {
+		return
+	}
+
+	now := o.nowFunc()
+	msg := fmt.Sprintf(format, args...)
+
+	fields := l.fields
+	if o.window > 0 && level < Error {
+		key := l.key(level, msg)
+		if s, ok := o.seen[key]; ok && now.Sub(s.last) < o.window {
+			s.suppressed++
+			return
+		} else if ok && s.suppressed > 0 {
+			fields = append(fields[:len(fields):len(fields)], field{"repeated", strconv.Itoa(s.suppressed)})
+		}
+
+		o.seen[key] = &seen{last: now, level: level, msg: msg, fields: l.fields}
+		o.sweep(now)
+	}
+
+	o.write(now, level, msg, fields)
+}
+
+// write formats and writes a record, must be called with mutex held
+func (o *output) write(now time.Time, level Level, msg string, fields []field) {
+	var line string
+	if o.format == JSON {
+		line = formatJSON(now, level, msg, fields)
+	} else {
+		line = formatLogfmt(now, level, msg, fields)
+	}
+
+	_, _ = io.WriteString(o.w, line)
+}
+
+// key returns deduplication key of a record
+func (l *Logger) key(level Level, msg string) string {
+	b := strings.Builder{}
+	b.WriteString(level.String())
+	for _, f := range l.fields {
+		b.WriteString("\xff" + f.key + "=" + f.value)
+	}
+	b.WriteString("\xff" + msg)
+
+	return b.String()
+}
+
+// sweep forgets records that are outside of deduplication window,
+// writing how many times suppressed records were repeated,
+// must be called with mutex held
+func (o *output) sweep(now time.Time) {
+	if now.Sub(o.swept) < o.window {
+		return
+	}
+
+	keys := make([]string, 0, len(o.seen))
+	for k := range o.seen {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := o.seen[k]
+		if now.Sub(s.last) < o.window {
+			continue
+		}
+
+		if s.suppressed > 0 {
+			fields := append(s.fields[:len(s.fields):len(s.fields)], field{"repeated", strconv.Itoa(s.suppressed)})
+			o.write(now, s.level, s.msg, fields)
+		}
+
+		delete(o.seen, k)
+	}
+
+	o.swept = now
+}
+
+// formatLogfmt formats a record as key=value pairs
+func formatLogfmt(now time.Time, level Level, msg string, fields []field) string {
+	b := strings.Builder{}
+
+	b.WriteString("time=" + now.UTC().Format(time.RFC3339Nano))
+	b.WriteString(" level=" + level.String())
+
+	for _, f := range fields {
+		b.WriteString(" " + f.key + "=" + logfmtValue(f.value))
+	}
+
+	b.WriteString(" msg=" + logfmtValue(msg) + "\n")
+
+	return b.String()
+}
+
+// logfmtValue quotes value if needed
+func logfmtValue(s string) string {
+	if s == "" || strings.ContainsAny(s, " =\"\\\n\t") {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
+
+// formatJSON formats a record as a json object
+func formatJSON(now time.Time, level Level, msg string, fields []field) string {
+	r := map[string]string{}
+	keys := []string{}
+
+	for _, f := range fields {
+		if _, ok := r[f.key]; !ok {
+			keys = append(keys, f.key)
+		}
+		r[f.key] = f.value
+	}
+
+	sort.Strings(keys)
+
+	b := strings.Builder{}
+	b.WriteString(`{"time":` + jsonString(now.UTC().Format(time.RFC3339Nano)))
+	b.WriteString(`,"level":` + jsonString(level.String()))
+
+	for _, k := range keys {
+		if k == "time" || k == "level" || k == "msg" {
+			continue
+		}
+
+		b.WriteString("," + jsonString(k) + ":" + jsonString(r[k]))
+	}
+
+	b.WriteString(`,"msg":` + jsonString(msg) + "}\n")
+
+	return b.String()
+}
+
+// jsonString returns json representation of a string
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	now := time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	b := bytes.NewBuffer(nil)
+	o := &output{
+		w:       b,
+		format:  Logfmt,
+		level:   Info,
+		window:  time.Minute,
+		seen:    map[string]*seen{},
+		nowFunc: func() time.Time { return now },
+	}
+
+	l := (&Logger{out: o}).With("component", "test")
+
+	l.Debugf("hidden")
+	l.With("task", "foo.1").Warnf("no label %s", "zoidberg_port_0_app_name")
+	l.With("task", "foo.1").Warnf("no label %s", "zoidberg_port_0_app_name")
+	l.With("task", "foo.1").Warnf("no label %s", "zoidberg_port_0_app_name")
+	l.With("task", "foo.2").Warnf("no label %s", "zoidberg_port_0_app_name")
+	l.With("task", "foo.2").Warnf("no label %s", "zoidberg_port_0_app_name")
+	l.Errorf("failed")
+	l.Errorf("failed")
+
+	now = now.Add(time.Minute)
+	l.With("task", "foo.1").Warnf("no label %s", "zoidberg_port_0_app_name")
+
+	o.format = JSON
+	l.Errorf("error with \"quotes\"")
+
+	expected := "" +
+		"time=2017-01-02T03:04:05Z level=warn component=test task=foo.1 msg=\"no label zoidberg_port_0_app_name\"\n" +
+		"time=2017-01-02T03:04:05Z level=warn component=test task=foo.2 msg=\"no label zoidberg_port_0_app_name\"\n" +
+		"time=2017-01-02T03:04:05Z level=error component=test msg=failed\n" +
+		"time=2017-01-02T03:04:05Z level=error component=test msg=failed\n" +
+		"time=2017-01-02T03:05:05Z level=warn component=test task=foo.2 repeated=1 msg=\"no label zoidberg_port_0_app_name\"\n" +
+		"time=2017-01-02T03:05:05Z level=warn component=test task=foo.1 repeated=2 msg=\"no label zoidberg_port_0_app_name\"\n" +
+		"{\"time\":\"2017-01-02T03:05:05Z\",\"level\":\"error\",\"component\":\"test\",\"msg\":\"error with \\\"quotes\\\"\"}\n"
+
+	if b.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
@@ -1,26 +1,44 @@
 package marathon
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gambol99/go-marathon"
 )
 
+// App is a Marathon app along with port mappings of its container
+type App struct {
+	marathon.Application
+	PortMappings []marathon.PortMapping
+}
+
 // AppFetcher fetches apps from Marathon
 type AppFetcher struct {
-	m marathon.Marathon
+	m        marathon.Marathon
+	mappings *containerMappings
+	mutex    sync.Mutex
 }
 
 // NewAppFetcher makes a new AppFetcher with the specified Marathon location
 func NewAppFetcher(u string) (*AppFetcher, error) {
+	mappings := &containerMappings{
+		next: http.DefaultTransport,
+	}
+
 	mc, err := marathon.NewClient(marathon.Config{
 		URL: u,
 		HTTPClient: &http.Client{
-			Timeout: time.Second * 8,
+			Timeout:   time.Second * 8,
+			Transport: mappings,
 		},
 		LogOutput: ioutil.Discard,
 	})
@@ -29,22 +47,125 @@ func NewAppFetcher(u string) (*AppFetcher, error) {
 	}
 
 	return &AppFetcher{
-		m: mc,
+		m:        mc,
+		mappings: mappings,
 	}, nil
 }
 
 // FetchApps fetches apps with specific label set to specific value
-func (a *AppFetcher) FetchApps(labels map[string]string) ([]marathon.Application, error) {
+func (a *AppFetcher) FetchApps(ctx context.Context, labels map[string]string) ([]App, error) {
 	mv := url.Values{}
-	mv.Set("embed", "apps.tasks")
 	for k, v := range labels {
 		mv.Set("label", fmt.Sprintf("%s==%s", k, v))
 	}
 
+	return a.fetch(ctx, mv)
+}
+
+// FetchAppsWithLabel fetches apps with specific label set to any value
+func (a *AppFetcher) FetchAppsWithLabel(ctx context.Context, label string) ([]App, error) {
+	mv := url.Values{}
+	mv.Set("label", label)
+
+	return a.fetch(ctx, mv)
+}
+
+// fetch fetches apps with embedded tasks matching specified query,
+// marathon client does not support cancellation of requests in flight
+func (a *AppFetcher) fetch(ctx context.Context, mv url.Values) ([]App, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	mv.Set("embed", "apps.tasks")
+
+	// port mappings are remembered from the last response with apps,
+	// so only one request is allowed to be in flight at a time
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
 	ma, err := a.m.Applications(mv)
 	if err != nil {
 		return nil, err
 	}
 
-	return ma.Apps, nil
+	mappings := a.mappings.take()
+
+	apps := make([]App, 0, len(ma.Apps))
+	for _, app := range ma.Apps {
+		pm := mappings[app.ID]
+		if len(pm) == 0 && app.Container != nil && app.Container.Docker != nil && app.Container.Docker.PortMappings != nil {
+			pm = *app.Container.Docker.PortMappings
+		}
+
+		apps = append(apps, App{
+			Application:  app,
+			PortMappings: pm,
+		})
+	}
+
+	return apps, nil
+}
+
+// containerMappings is a transport that remembers port mappings of app
+// containers from responses with apps, they live in container since
+// Marathon 1.5, but go-marathon only knows about docker port mappings
+type containerMappings struct {
+	next  http.RoundTripper
+	apps  map[string][]marathon.PortMapping
+	mutex sync.Mutex
+}
+
+// RoundTrip performs the request and remembers port mappings of apps
+func (c *containerMappings) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := c.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK || !strings.HasSuffix(req.URL.Path, "/v2/apps") {
+		return resp, err
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	parsed := struct {
+		Apps []struct {
+			ID        string `json:"id"`
+			Container *struct {
+				PortMappings []marathon.PortMapping `json:"portMappings"`
+			} `json:"container"`
+		} `json:"apps"`
+	}{}
+
+	// invalid responses are reported by marathon client
+	if json.Unmarshal(b, &parsed) != nil {
+		return resp, nil
+	}
+
+	apps := map[string][]marathon.PortMapping{}
+	for _, app := range parsed.Apps {
+		if app.Container != nil {
+			apps[app.ID] = app.Container.PortMappings
+		}
+	}
+
+	c.mutex.Lock()
+	c.apps = apps
+	c.mutex.Unlock()
+
+	return resp, nil
+}
+
+// take returns port mappings from the last response with apps and forgets them
+func (c *containerMappings) take() map[string][]marathon.PortMapping {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	apps := c.apps
+	c.apps = nil
+
+	return apps
 }
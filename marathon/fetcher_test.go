@@ -0,0 +1,62 @@
+package marathon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAppFetcherPortMappings(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/apps" || r.URL.Query().Get("embed") != "apps.tasks" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write([]byte(`{"apps":[
+			{"id":"/mesos","container":{"type":"MESOS","portMappings":[{"containerPort":8080},{"containerPort":9090}]}},
+			{"id":"/docker","container":{"type":"DOCKER","docker":{"image":"nginx","portMappings":[{"containerPort":80}]}}},
+			{"id":"/host","tasks":[{"id":"host.1","host":"h1","ports":[31000]}]}
+		]}`))
+	}))
+
+	defer s.Close()
+
+	// marathon client moves on to the next member if one is unreachable
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	f, err := NewAppFetcher(down.URL + "," + strings.TrimPrefix(s.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apps, err := f.FetchApps(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]int{
+		"/mesos":  {8080, 9090},
+		"/docker": {80},
+		"/host":   {},
+	}
+
+	if len(apps) != len(expected) {
+		t.Fatalf("expected %d apps, got %d", len(expected), len(apps))
+	}
+
+	for _, app := range apps {
+		ports := []int{}
+		for _, m := range app.PortMappings {
+			ports = append(ports, m.ContainerPort)
+		}
+
+		if !reflect.DeepEqual(ports, expected[app.ID]) {
+			t.Errorf("app %s: expected container ports %v, got %v", app.ID, expected[app.ID], ports)
+		}
+	}
+}
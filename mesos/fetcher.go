@@ -1,58 +1,118 @@
 package mesos
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/bobrik/zoidberg/logging"
 )
 
+var logger = logging.Component("mesos")
+
 // ErrNoMesosMaster indicates that no alive mesos masters are found
 var ErrNoMesosMaster = errors.New("mesos master not found")
 
-// TaskFetcher fetches Mesos tasks from Mesos masters
+// APIs of Mesos masters to fetch tasks from
+const (
+	// APIState is the deprecated /state.json endpoint
+	APIState = "state"
+	// APIV1 is the v1 operator api
+	APIV1 = "v1"
+)
+
+// Fetcher fetches tasks running on Mesos cluster
+type Fetcher interface {
+	FetchTasks(ctx context.Context) ([]Task, error)
+}
+
+// fetchers are fetchers shared by masters and api
+var (
+	fetchers      = map[string]Fetcher{}
+	fetchersMutex sync.Mutex
+)
+
+// NewFetcher returns a Fetcher that uses the specified api, fetchers are
+// shared between callers with the same masters and api, so leaders are
+// detected and events are subscribed to once for all of them
+func NewFetcher(masters []string, api string) (Fetcher, error) {
+	if api == "" {
+		api = APIState
+	}
+
+	key := api + ":" + strings.Join(masters, ",")
+
+	fetchersMutex.Lock()
+	defer fetchersMutex.Unlock()
+
+	if f, ok := fetchers[key]; ok {
+		return f, nil
+	}
+
+	var f Fetcher
+	var err error
+
+	switch api {
+	case APIState:
+		f, err = NewTaskFetcher(masters)
+	case APIV1:
+		f, err = NewOperatorFetcher(masters)
+	default:
+		return nil, fmt.Errorf("unknown mesos api %q, expected %q or %q", api, APIState, APIV1)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	fetchers[key] = f
+
+	return f, nil
+}
+
+// TaskFetcher fetches Mesos tasks from the leading Mesos master
 type TaskFetcher struct {
-	masters []string
-	client  http.Client
+	leader *leaderDetector
+	client http.Client
 }
 
 // NewTaskFetcher creates a new TaskFetcher with specified Mesos masters
-func NewTaskFetcher(masters []string) *TaskFetcher {
+func NewTaskFetcher(masters []string) (*TaskFetcher, error) {
+	d, err := newLeaderDetector(masters)
+	if err != nil {
+		return nil, err
+	}
+
 	return &TaskFetcher{
-		masters: masters,
+		leader: d,
 		client: http.Client{
 			Timeout: time.Second * 5,
 		},
-	}
+	}, nil
 }
 
-// FetchTasks returns tasks currently running on Mesos cluster
-func (f *TaskFetcher) FetchTasks() ([]Task, error) {
-	s := mesosState{}
-
-	for _, master := range f.masters {
-		u := master + "/state.json"
-		resp, err := f.client.Get(u)
+// FetchTasks returns tasks currently running on Mesos cluster,
+// the leader is detected again once if the cached one fails
+func (f *TaskFetcher) FetchTasks(ctx context.Context) ([]Task, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		leader, err := f.leader.Leader(ctx)
 		if err != nil {
-			log.Printf("error fetching state from %s: %s", u, err)
-			continue
+			return nil, err
 		}
 
-		defer func() {
-			err = resp.Body.Close()
-			if err != nil {
-				log.Printf("error closing body from %s: %s", u, err)
-			}
-		}()
-
-		err = json.NewDecoder(resp.Body).Decode(&s)
-		if err != nil {
-			log.Printf("error decoding state from %s: %s", u, err)
-			continue
+		s, err := f.fetchState(ctx, leader)
+		if err == nil && s.Pid != s.Leader {
+			err = errors.New("master is not leading")
 		}
 
-		if s.Pid != s.Leader {
+		if err != nil {
+			logger.With("master", leader).Warnf("error fetching state: %s", err)
+			f.leader.Reset(leader)
 			continue
 		}
 
@@ -62,6 +122,39 @@ func (f *TaskFetcher) FetchTasks() ([]Task, error) {
 	return nil, ErrNoMesosMaster
 }
 
+// fetchState returns state of the master
+func (f *TaskFetcher) fetchState(ctx context.Context, master string) (mesosState, error) {
+	s := mesosState{}
+
+	req, err := http.NewRequest("GET", master+"/state.json", nil)
+	if err != nil {
+		return s, err
+	}
+
+	resp, err := f.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return s, err
+	}
+
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			logger.With("master", master).Warnf("error closing body: %s", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return s, fmt.Errorf("unexpected response code %d", resp.StatusCode)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&s)
+	if err != nil {
+		return s, fmt.Errorf("error decoding state: %s", err)
+	}
+
+	return s, nil
+}
+
 // tasksFromLeader returns tasks from the currently leading Mesos master
 func (f *TaskFetcher) tasksFromLeader(s mesosState) ([]Task, error) {
 	hosts := map[string]string{}
@@ -69,30 +162,44 @@ func (f *TaskFetcher) tasksFromLeader(s mesosState) ([]Task, error) {
 		hosts[s.ID] = s.Host
 	}
 
-	tasks := []Task{}
+	tasks := []mesosTask{}
 	for _, f := range s.Frameworks {
-		for _, t := range f.Tasks {
-			if t.State != "TASK_RUNNING" {
-				continue
-			}
-
-			if len(t.Resources.Ports) == 0 {
-				continue
-			}
-
-			labels := map[string]string{}
-			for _, l := range t.Labels {
-				labels[l.Key] = l.Value
-			}
-
-			tasks = append(tasks, Task{
-				Name:   t.Name,
-				Host:   hosts[t.SlaveID],
-				Ports:  t.Resources.Ports,
-				Labels: labels,
-			})
+		tasks = append(tasks, f.Tasks...)
+	}
+
+	return runningTasks(hosts, tasks), nil
+}
+
+// runningTasks returns running tasks with ports, hosts
+// map agent ids to their hostnames
+func runningTasks(hosts map[string]string, mesosTasks []mesosTask) []Task {
+	tasks := []Task{}
+	for _, t := range mesosTasks {
+		if t.State != "TASK_RUNNING" {
+			continue
+		}
+
+		containerPorts := t.containerPorts()
+		if len(t.Resources.Ports) == 0 && len(containerPorts) == 0 {
+			continue
 		}
+
+		labels := map[string]string{}
+		for _, l := range t.Labels {
+			labels[l.Key] = l.Value
+		}
+
+		tasks = append(tasks, Task{
+			Name:           t.Name,
+			Host:           hosts[t.SlaveID],
+			Ports:          t.Resources.Ports,
+			IPAddresses:    t.ipAddresses(),
+			ContainerPorts: containerPorts,
+			Labels:         labels,
+			HealthChecked:  t.HealthCheck != nil,
+			Healthy:        t.healthy(),
+		})
 	}
 
-	return tasks, nil
+	return tasks
 }
@@ -0,0 +1,41 @@
+package mesos
+
+import "testing"
+
+func TestNewFetcherShared(t *testing.T) {
+	state, err := NewFetcher([]string{"http://shared-master:5050"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := NewFetcher([]string{"http://shared-master:5050"}, APIState)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state != again {
+		t.Errorf("expected fetcher to be shared for the same masters and api")
+	}
+
+	v1, err := NewFetcher([]string{"http://shared-master:5050"}, APIV1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := v1.(*OperatorFetcher); !ok || v1 == state {
+		t.Errorf("expected a separate operator fetcher for v1 api, got %#v", v1)
+	}
+
+	other, err := NewFetcher([]string{"http://other-master:5050"}, APIState)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if other == state {
+		t.Errorf("expected a separate fetcher for other masters")
+	}
+
+	if _, err = NewFetcher([]string{"http://shared-master:5050"}, "v2"); err == nil {
+		t.Errorf("expected error for unknown api")
+	}
+}
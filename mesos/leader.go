@@ -0,0 +1,290 @@
+package mesos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// zkInfoPrefix is the prefix of znodes with json info of masters
+const zkInfoPrefix = "json.info_"
+
+// leaderDetector finds the leading master either in zookeeper or by
+// asking masters to redirect to the leader, the leader is cached
+// until it is reset after failure
+type leaderDetector struct {
+	masters []string
+	zk      []string
+	zkPath  string
+	scheme  string
+	client  http.Client
+	leader  string
+	mutex   sync.Mutex
+}
+
+// newLeaderDetector creates a new detector for masters, which are either
+// urls in http://host:port format or a single zk://host:port[,host:port]/path
+// url, it's also accepted split by commas, masters found in zookeeper
+// are reached over http unless ?scheme=https is added to the url
+func newLeaderDetector(masters []string) (*leaderDetector, error) {
+	d := &leaderDetector{
+		masters: masters,
+		scheme:  "http",
+		client: http.Client{
+			Timeout: time.Second * 5,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+
+	if len(masters) > 0 && strings.HasPrefix(masters[0], "zk://") {
+		u := strings.TrimPrefix(strings.Join(masters, ","), "zk://")
+
+		if i := strings.Index(u, "?"); i >= 0 {
+			query, err := url.ParseQuery(u[i+1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid query in mesos masters %q: %s", "zk://"+u, err)
+			}
+
+			for k := range query {
+				if k != "scheme" {
+					return nil, fmt.Errorf("unknown parameter %q in mesos masters %q", k, "zk://"+u)
+				}
+			}
+
+			d.scheme = query.Get("scheme")
+			if d.scheme != "http" && d.scheme != "https" {
+				return nil, fmt.Errorf("unknown scheme %q in mesos masters %q, expected http or https", d.scheme, "zk://"+u)
+			}
+
+			u = u[:i]
+		}
+
+		i := strings.Index(u, "/")
+		if i < 0 || i == len(u)-1 {
+			return nil, fmt.Errorf("expected zookeeper path in mesos masters %q", "zk://"+u)
+		}
+
+		d.zk = strings.Split(u[:i], ",")
+		d.zkPath = strings.TrimSuffix(u[i:], "/")
+	}
+
+	return d, nil
+}
+
+// Leader returns url of the leading master
+func (d *leaderDetector) Leader(ctx context.Context) (string, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.leader != "" {
+		return d.leader, nil
+	}
+
+	var leader string
+	var err error
+
+	if len(d.zk) > 0 {
+		leader, err = d.zkLeader(ctx)
+	} else {
+		leader, err = d.redirectLeader(ctx)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if leader != d.leader {
+		logger.With("leader", leader).Infof("detected leading master")
+	}
+
+	d.leader = leader
+
+	return leader, nil
+}
+
+// Reset forgets the leader if it's still the cached one,
+// so it is detected again on the next call
+func (d *leaderDetector) Reset(leader string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.leader == leader {
+		d.leader = ""
+	}
+}
+
+// redirectLeader asks masters in turn to redirect to the leader
+func (d *leaderDetector) redirectLeader(ctx context.Context) (string, error) {
+	for _, master := range d.masters {
+		leader, err := d.redirect(ctx, master)
+		if err != nil {
+			logger.With("master", master).Debugf("error detecting leader: %s", err)
+			continue
+		}
+
+		return leader, nil
+	}
+
+	return "", ErrNoMesosMaster
+}
+
+// redirect returns leader url from redirect of the master
+func (d *leaderDetector) redirect(ctx context.Context, master string) (string, error) {
+	base, err := url.Parse(master)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest("GET", master+"/master/redirect", nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := d.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTemporaryRedirect {
+		return "", fmt.Errorf("expected redirect, got response code %d", resp.StatusCode)
+	}
+
+	location, err := base.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", fmt.Errorf("invalid redirect location: %s", err)
+	}
+
+	if location.Host == "" {
+		return "", errors.New("no leader in redirect location")
+	}
+
+	return location.Scheme + "://" + location.Host, nil
+}
+
+// zkLeader returns leader url from master info in zookeeper
+func (d *leaderDetector) zkLeader(ctx context.Context) (string, error) {
+	conn, _, err := zk.Connect(d.zk, time.Second*10)
+	if err != nil {
+		return "", err
+	}
+
+	conn.SetLogger(zkLogger{})
+
+	// requests wait for connection, closing the connection cancels them
+	defer conn.Close()
+
+	type result struct {
+		leader string
+		err    error
+	}
+
+	done := make(chan result, 1)
+
+	go func() {
+		children, _, err := conn.Children(d.zkPath)
+		if err != nil {
+			done <- result{err: fmt.Errorf("error listing %s: %s", d.zkPath, err)}
+			return
+		}
+
+		leader, err := leaderFromInfo(children, d.scheme, func(name string) ([]byte, error) {
+			b, _, err := conn.Get(d.zkPath + "/" + name)
+			return b, err
+		})
+
+		done <- result{leader: leader, err: err}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return "", fmt.Errorf("error detecting leader in zookeeper: %s", ctx.Err())
+	case r := <-done:
+		return r.leader, r.err
+	}
+}
+
+// masterInfo is json info of master in zookeeper
+type masterInfo struct {
+	Hostname string `json:"hostname"`
+	Port     int    `json:"port"`
+	Address  struct {
+		Hostname string `json:"hostname"`
+		IP       string `json:"ip"`
+		Port     int    `json:"port"`
+	} `json:"address"`
+}
+
+// leaderFromInfo returns url of the leader with the scheme from json info
+// znodes, the znode with the lowest sequence number belongs to the leader
+func leaderFromInfo(children []string, scheme string, get func(name string) ([]byte, error)) (string, error) {
+	infos := []string{}
+	for _, c := range children {
+		if strings.HasPrefix(c, zkInfoPrefix) {
+			infos = append(infos, c)
+		}
+	}
+
+	if len(infos) == 0 {
+		return "", ErrNoMesosMaster
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i][len(zkInfoPrefix):] < infos[j][len(zkInfoPrefix):]
+	})
+
+	b, err := get(infos[0])
+	if err != nil {
+		return "", fmt.Errorf("error getting %s: %s", infos[0], err)
+	}
+
+	info := masterInfo{}
+	err = json.Unmarshal(b, &info)
+	if err != nil {
+		return "", fmt.Errorf("error decoding %s: %s", infos[0], err)
+	}
+
+	host := info.Address.Hostname
+	if host == "" {
+		host = info.Hostname
+	}
+
+	if host == "" {
+		host = info.Address.IP
+	}
+
+	port := info.Address.Port
+	if port == 0 {
+		port = info.Port
+	}
+
+	if host == "" || port == 0 {
+		return "", fmt.Errorf("no address of the leader in %s", infos[0])
+	}
+
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// zkLogger sends logs of zookeeper client to debug level
+type zkLogger struct{}
+
+func (zkLogger) Printf(format string, args ...interface{}) {
+	logger.Debugf(format, args...)
+}
@@ -0,0 +1,229 @@
+package mesos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLeaderFromInfo(t *testing.T) {
+	table := []struct {
+		children []string
+		infos    map[string]string
+		scheme   string
+		leader   string
+		err      bool
+	}{
+		{
+			children: []string{"log_replicas", "json.info_0000000012", "info_0000000011", "json.info_0000000010"},
+			infos: map[string]string{
+				"json.info_0000000010": `{"hostname": "master1", "port": 5050, "address": {"hostname": "master1.example.com", "ip": "10.0.0.1", "port": 5050}}`,
+				"json.info_0000000012": `{"hostname": "master2", "port": 5050}`,
+			},
+			leader: "http://master1.example.com:5050",
+		},
+		{
+			children: []string{"json.info_0000000001"},
+			infos: map[string]string{
+				"json.info_0000000001": `{"address": {"ip": "2001:db8::1", "port": 5050}}`,
+			},
+			leader: "http://[2001:db8::1]:5050",
+		},
+		{
+			children: []string{"json.info_0000000001"},
+			infos: map[string]string{
+				"json.info_0000000001": `{"hostname": "master1", "port": 5050}`,
+			},
+			scheme: "https",
+			leader: "https://master1:5050",
+		},
+		{
+			children: []string{"json.info_0000000001"},
+			infos: map[string]string{
+				"json.info_0000000001": `{"hostname": "master1"}`,
+			},
+			err: true,
+		},
+		{
+			children: []string{"log_replicas"},
+			err:      true,
+		},
+	}
+
+	for i, row := range table {
+		scheme := row.scheme
+		if scheme == "" {
+			scheme = "http"
+		}
+
+		leader, err := leaderFromInfo(row.children, scheme, func(name string) ([]byte, error) {
+			if info, ok := row.infos[name]; ok {
+				return []byte(info), nil
+			}
+
+			return nil, errors.New("no node")
+		})
+
+		if row.err {
+			if err == nil {
+				t.Errorf("row %d: expected error, got: %q", i, leader)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("row %d: unexpected error: %s", i, err)
+			continue
+		}
+
+		if leader != row.leader {
+			t.Errorf("row %d: expected: %q, got: %q", i, row.leader, leader)
+		}
+	}
+}
+
+func TestNewLeaderDetector(t *testing.T) {
+	table := []struct {
+		masters []string
+		zk      []string
+		path    string
+		scheme  string
+		err     bool
+	}{
+		{
+			masters: []string{"http://master1:5050", "http://master2:5050"},
+			scheme:  "http",
+		},
+		{
+			masters: []string{"zk://zk1:2181", "zk2:2181/mesos"},
+			zk:      []string{"zk1:2181", "zk2:2181"},
+			path:    "/mesos",
+			scheme:  "http",
+		},
+		{
+			masters: []string{"zk://zk1:2181/mesos?scheme=https"},
+			zk:      []string{"zk1:2181"},
+			path:    "/mesos",
+			scheme:  "https",
+		},
+		{
+			masters: []string{"zk://zk1:2181/mesos?scheme=ftp"},
+			err:     true,
+		},
+		{
+			masters: []string{"zk://zk1:2181/mesos?tls=true"},
+			err:     true,
+		},
+		{
+			masters: []string{"zk://zk1:2181/"},
+			err:     true,
+		},
+	}
+
+	for i, row := range table {
+		d, err := newLeaderDetector(row.masters)
+		if row.err {
+			if err == nil {
+				t.Errorf("row %d: expected error", i)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("row %d: unexpected error: %s", i, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(d.zk, row.zk) || d.zkPath != row.path || d.scheme != row.scheme {
+			t.Errorf("row %d: expected: %v %q %q, got: %v %q %q", i, row.zk, row.path, row.scheme, d.zk, d.zkPath, d.scheme)
+		}
+	}
+}
+
+// fakeMaster redirects to the leader and serves state if it is leading
+type fakeMaster struct {
+	host     string
+	leader   string
+	failing  bool
+	requests map[string]int
+	mutex    sync.Mutex
+}
+
+func (m *fakeMaster) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.requests[req.URL.Path]++
+
+	switch req.URL.Path {
+	case "/master/redirect":
+		http.Redirect(w, req, "//"+m.leader, http.StatusTemporaryRedirect)
+	case "/state.json":
+		if m.failing {
+			http.Error(w, "failing", http.StatusServiceUnavailable)
+			return
+		}
+
+		fmt.Fprintf(w, `{"pid": "master@%s", "leader": "master@%s", "slaves": [], "frameworks": []}`, m.host, m.leader)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func TestTaskFetcherLeader(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	leader := &fakeMaster{requests: map[string]int{}}
+	ls := httptest.NewServer(leader)
+	defer ls.Close()
+
+	host := strings.TrimPrefix(ls.URL, "http://")
+	leader.host, leader.leader = host, host
+
+	follower := &fakeMaster{requests: map[string]int{}, leader: host}
+	fs := httptest.NewServer(follower)
+	defer fs.Close()
+
+	follower.host = strings.TrimPrefix(fs.URL, "http://")
+
+	f, err := NewTaskFetcher([]string{down.URL, fs.URL, ls.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err = f.FetchTasks(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	follower.mutex.Lock()
+	if follower.requests["/master/redirect"] != 1 || follower.requests["/state.json"] != 0 {
+		t.Errorf("expected leader to be detected once without fetching state from follower, got: %v", follower.requests)
+	}
+	follower.mutex.Unlock()
+
+	// failing leader is detected again
+	leader.mutex.Lock()
+	leader.failing = true
+	leader.mutex.Unlock()
+
+	if _, err = f.FetchTasks(context.Background()); err == nil {
+		t.Errorf("expected error from failing leader")
+	}
+
+	follower.mutex.Lock()
+	if follower.requests["/master/redirect"] != 2 {
+		t.Errorf("expected leader to be detected again, got: %v", follower.requests)
+	}
+	follower.mutex.Unlock()
+}
@@ -0,0 +1,355 @@
+package mesos
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// operatorRetry is how long to wait after failed subscriptions
+	operatorRetry = time.Second * 5
+	// operatorIdle is how long subscription is kept without being used
+	operatorIdle = time.Minute * 10
+	// operatorStatuses is how many recent statuses are kept for tasks
+	operatorStatuses = 10
+)
+
+// terminalStates are states of tasks that are not going to run again
+var terminalStates = map[string]bool{
+	"TASK_FINISHED":         true,
+	"TASK_FAILED":           true,
+	"TASK_KILLED":           true,
+	"TASK_ERROR":            true,
+	"TASK_LOST":             true,
+	"TASK_DROPPED":          true,
+	"TASK_GONE":             true,
+	"TASK_GONE_BY_OPERATOR": true,
+}
+
+// OperatorFetcher fetches Mesos tasks with v1 operator api, it subscribes
+// to events of the leading master after the first use and keeps tasks
+// in memory, tasks are polled until subscription is established
+type OperatorFetcher struct {
+	leader *leaderDetector
+	client http.Client
+	stream http.Client
+
+	running    bool
+	subscribed bool
+	used       time.Time
+	tasks      map[string]operatorTask
+	agents     map[string]string
+	mutex      sync.Mutex
+}
+
+// NewOperatorFetcher creates a new OperatorFetcher with specified Mesos masters
+func NewOperatorFetcher(masters []string) (*OperatorFetcher, error) {
+	d, err := newLeaderDetector(masters)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OperatorFetcher{
+		leader: d,
+		client: http.Client{
+			Timeout: time.Second * 5,
+		},
+	}, nil
+}
+
+// FetchTasks returns tasks currently running on Mesos cluster
+func (f *OperatorFetcher) FetchTasks(ctx context.Context) ([]Task, error) {
+	f.mutex.Lock()
+	f.used = time.Now()
+
+	if !f.running {
+		f.running = true
+		go f.subscribe()
+	}
+
+	if f.subscribed {
+		tasks := viewTasks(f.agents, f.tasks)
+		f.mutex.Unlock()
+		return tasks, nil
+	}
+
+	f.mutex.Unlock()
+
+	for attempt := 0; attempt < 2; attempt++ {
+		leader, err := f.leader.Leader(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		tasks, agents, err := f.poll(ctx, leader)
+		if err != nil {
+			logger.With("master", leader).Warnf("error fetching tasks: %s", err)
+			f.leader.Reset(leader)
+			continue
+		}
+
+		return viewTasks(agents, tasks), nil
+	}
+
+	return nil, ErrNoMesosMaster
+}
+
+// poll returns tasks and hostnames of agents from the master
+func (f *OperatorFetcher) poll(ctx context.Context, master string) (map[string]operatorTask, map[string]string, error) {
+	r := operatorResponse{}
+
+	err := f.call(ctx, master, "GET_TASKS", &r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if r.GetTasks == nil {
+		return nil, nil, fmt.Errorf("unexpected response of type %q to GET_TASKS", r.Type)
+	}
+
+	tasks := r.GetTasks.byKey()
+
+	r = operatorResponse{}
+
+	err = f.call(ctx, master, "GET_AGENTS", &r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if r.GetAgents == nil {
+		return nil, nil, fmt.Errorf("unexpected response of type %q to GET_AGENTS", r.Type)
+	}
+
+	return tasks, r.GetAgents.hosts(), nil
+}
+
+// call makes a call of the type to operator api of the master
+func (f *OperatorFetcher) call(ctx context.Context, master, call string, result interface{}) error {
+	resp, err := f.request(ctx, &f.client, master, call)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			logger.With("master", master).Warnf("error closing body: %s", err)
+		}
+	}()
+
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return fmt.Errorf("error decoding response to %s: %s", call, err)
+	}
+
+	return nil
+}
+
+// request sends a call of the type to operator api of the master
+func (f *OperatorFetcher) request(ctx context.Context, client *http.Client, master, call string) (*http.Response, error) {
+	body := fmt.Sprintf(`{"type":%q}`, call)
+
+	req, err := http.NewRequest("POST", master+"/api/v1", bytes.NewReader([]byte(body)))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected response code %d to %s: %s", resp.StatusCode, call, strings.TrimSpace(string(b)))
+	}
+
+	return resp, nil
+}
+
+// subscribe keeps subscription to events of the leading master
+// until fetcher is not used for a while
+func (f *OperatorFetcher) subscribe() {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		leader, err := f.leader.Leader(ctx)
+		cancel()
+
+		if err == nil {
+			err = f.watch(leader)
+			f.leader.Reset(leader)
+		}
+
+		f.mutex.Lock()
+		f.subscribed = false
+		idle := time.Since(f.used) > operatorIdle
+		if idle {
+			f.running = false
+			f.tasks = nil
+			f.agents = nil
+		}
+		f.mutex.Unlock()
+
+		if idle {
+			logger.Debugf("stopping idle subscription")
+			return
+		}
+
+		logger.With("master", leader).Warnf("subscription failed: %s", err)
+
+		time.Sleep(operatorRetry)
+	}
+}
+
+// watch subscribes to events of the master and applies them to tasks
+// in memory, it returns when subscription fails or is not used anymore
+func (f *OperatorFetcher) watch(master string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the first event is the whole state, which may take a while
+	timeout := time.AfterFunc(time.Minute, cancel)
+	defer timeout.Stop()
+
+	resp, err := f.request(ctx, &f.stream, master, "SUBSCRIBE")
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	r := bufio.NewReader(resp.Body)
+
+	heartbeat := time.Duration(0)
+
+	for {
+		b, err := readRecord(r)
+		if err != nil {
+			return err
+		}
+
+		e := operatorEvent{}
+		err = json.Unmarshal(b, &e)
+		if err != nil {
+			return fmt.Errorf("error decoding event: %s", err)
+		}
+
+		f.mutex.Lock()
+		idle := time.Since(f.used) > operatorIdle
+		if interval := f.apply(e); interval > 0 {
+			heartbeat = interval
+		}
+		f.mutex.Unlock()
+
+		if idle {
+			return nil
+		}
+
+		// subscription is considered dead after missing heartbeats,
+		// every record including heartbeats proves it is alive
+		if heartbeat > 0 {
+			timeout.Reset(heartbeat * 3)
+		}
+	}
+}
+
+// apply applies the event to tasks in memory and returns heartbeat
+// interval once subscribed, must be called with mutex held
+func (f *OperatorFetcher) apply(e operatorEvent) time.Duration {
+	switch {
+	case e.Subscribed != nil:
+		f.tasks = e.Subscribed.GetState.GetTasks.byKey()
+		f.agents = e.Subscribed.GetState.GetAgents.hosts()
+		f.subscribed = true
+
+		interval := time.Duration(e.Subscribed.HeartbeatIntervalSeconds * float64(time.Second))
+		if interval <= 0 {
+			interval = time.Second * 15
+		}
+
+		return interval
+	case !f.subscribed:
+		return 0
+	case e.TaskAdded != nil:
+		t := e.TaskAdded.Task
+		f.tasks[t.key()] = t
+	case e.TaskUpdated != nil:
+		u := e.TaskUpdated
+		key := u.FrameworkID.Value + "/" + u.Status.TaskID.Value
+
+		t, ok := f.tasks[key]
+		if !ok {
+			return 0
+		}
+
+		if terminalStates[u.State] {
+			delete(f.tasks, key)
+			return 0
+		}
+
+		t.State = u.State
+		t.Statuses = append(t.Statuses, u.Status.mesosTaskStatus)
+		if len(t.Statuses) > operatorStatuses {
+			t.Statuses = t.Statuses[len(t.Statuses)-operatorStatuses:]
+		}
+
+		f.tasks[key] = t
+	case e.AgentAdded != nil:
+		a := e.AgentAdded.Agent.AgentInfo
+		f.agents[a.ID.Value] = a.Hostname
+	case e.AgentRemoved != nil:
+		delete(f.agents, e.AgentRemoved.AgentID.Value)
+	}
+
+	return 0
+}
+
+// readRecord reads a single record of RecordIO stream,
+// each record is prefixed with its length and a newline
+func readRecord(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.ParseUint(strings.TrimSpace(line), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid record length: %s", err)
+	}
+
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+
+	return b, err
+}
+
+// viewTasks returns running tasks from tasks in memory in stable order
+func viewTasks(agents map[string]string, tasks map[string]operatorTask) []Task {
+	keys := make([]string, 0, len(tasks))
+	for k := range tasks {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	mesosTasks := make([]mesosTask, len(keys))
+	for i, k := range keys {
+		mesosTasks[i] = tasks[k].mesosTask()
+	}
+
+	return runningTasks(agents, mesosTasks)
+}
@@ -0,0 +1,178 @@
+package mesos
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+type operatorResponse struct {
+	Type      string          `json:"type"`
+	GetTasks  *operatorTasks  `json:"get_tasks"`
+	GetAgents *operatorAgents `json:"get_agents"`
+}
+
+type operatorEvent struct {
+	Type         string                `json:"type"`
+	Subscribed   *operatorSubscribed   `json:"subscribed"`
+	TaskAdded    *operatorTaskAdded    `json:"task_added"`
+	TaskUpdated  *operatorTaskUpdated  `json:"task_updated"`
+	AgentAdded   *operatorAgentAdded   `json:"agent_added"`
+	AgentRemoved *operatorAgentRemoved `json:"agent_removed"`
+}
+
+type operatorSubscribed struct {
+	GetState                 operatorState `json:"get_state"`
+	HeartbeatIntervalSeconds float64       `json:"heartbeat_interval_seconds"`
+}
+
+type operatorState struct {
+	GetTasks  operatorTasks  `json:"get_tasks"`
+	GetAgents operatorAgents `json:"get_agents"`
+}
+
+type operatorTaskAdded struct {
+	Task operatorTask `json:"task"`
+}
+
+type operatorTaskUpdated struct {
+	FrameworkID operatorID     `json:"framework_id"`
+	Status      operatorStatus `json:"status"`
+	State       string         `json:"state"`
+}
+
+type operatorAgentAdded struct {
+	Agent operatorAgent `json:"agent"`
+}
+
+type operatorAgentRemoved struct {
+	AgentID operatorID `json:"agent_id"`
+}
+
+type operatorTasks struct {
+	Tasks []operatorTask `json:"tasks"`
+}
+
+// byKey returns active tasks by their framework and task ids
+func (t operatorTasks) byKey() map[string]operatorTask {
+	tasks := make(map[string]operatorTask, len(t.Tasks))
+	for _, task := range t.Tasks {
+		tasks[task.key()] = task
+	}
+
+	return tasks
+}
+
+type operatorAgents struct {
+	Agents []operatorAgent `json:"agents"`
+}
+
+// hosts returns hostnames of agents by their ids
+func (a operatorAgents) hosts() map[string]string {
+	hosts := make(map[string]string, len(a.Agents))
+	for _, agent := range a.Agents {
+		hosts[agent.AgentInfo.ID.Value] = agent.AgentInfo.Hostname
+	}
+
+	return hosts
+}
+
+type operatorAgent struct {
+	AgentInfo operatorAgentInfo `json:"agent_info"`
+}
+
+type operatorAgentInfo struct {
+	ID       operatorID `json:"id"`
+	Hostname string     `json:"hostname"`
+}
+
+type operatorID struct {
+	Value string `json:"value"`
+}
+
+type operatorStatus struct {
+	mesosTaskStatus
+	TaskID operatorID `json:"task_id"`
+}
+
+type operatorTask struct {
+	Name        string             `json:"name"`
+	TaskID      operatorID         `json:"task_id"`
+	FrameworkID operatorID         `json:"framework_id"`
+	AgentID     operatorID         `json:"agent_id"`
+	State       string             `json:"state"`
+	Resources   []operatorResource `json:"resources"`
+	Labels      operatorLabels     `json:"labels"`
+	Statuses    []mesosTaskStatus  `json:"statuses"`
+	Container   mesosContainer     `json:"container"`
+	HealthCheck *json.RawMessage   `json:"health_check"`
+}
+
+// key returns framework and task ids of the task
+func (t operatorTask) key() string {
+	return t.FrameworkID.Value + "/" + t.TaskID.Value
+}
+
+// mesosTask converts the task to the model of state endpoint
+func (t operatorTask) mesosTask() mesosTask {
+	ports := mesosPorts{}
+	for _, r := range t.Resources {
+		if r.Name != "ports" {
+			continue
+		}
+
+		for _, pr := range r.Ranges.Range {
+			for p := pr.Begin; p <= pr.End; p++ {
+				ports = append(ports, int(p))
+			}
+		}
+	}
+
+	return mesosTask{
+		ID:          t.TaskID.Value,
+		Name:        t.Name,
+		State:       t.State,
+		SlaveID:     t.AgentID.Value,
+		Resources:   mesosResources{Ports: ports},
+		Labels:      t.Labels.Labels,
+		Statuses:    t.Statuses,
+		Container:   t.Container,
+		HealthCheck: t.HealthCheck,
+	}
+}
+
+type operatorLabels struct {
+	Labels []mesosLabel `json:"labels"`
+}
+
+type operatorResource struct {
+	Name   string         `json:"name"`
+	Ranges operatorRanges `json:"ranges"`
+}
+
+type operatorRanges struct {
+	Range []operatorRange `json:"range"`
+}
+
+type operatorRange struct {
+	Begin operatorUint `json:"begin"`
+	End   operatorUint `json:"end"`
+}
+
+// operatorUint is a 64 bit integer that may be encoded as a string
+type operatorUint uint64
+
+func (u *operatorUint) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if len(s) > 1 && s[0] == '"' {
+		s = s[1 : len(s)-1]
+	}
+
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*u = operatorUint(v)
+
+	return nil
+}
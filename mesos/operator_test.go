@@ -0,0 +1,218 @@
+package mesos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeOperator serves v1 operator api calls, events sent
+// to the channel are streamed to subscribers
+type fakeOperator struct {
+	tasks     string
+	agents    string
+	heartbeat float64
+	events    chan string
+	done      chan struct{}
+}
+
+func (f *fakeOperator) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/master/redirect" {
+		http.Redirect(w, req, "//"+req.Host, http.StatusTemporaryRedirect)
+		return
+	}
+
+	call := struct {
+		Type string `json:"type"`
+	}{}
+
+	if err := json.NewDecoder(req.Body).Decode(&call); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch call.Type {
+	case "GET_TASKS":
+		fmt.Fprintf(w, `{"type":"GET_TASKS","get_tasks":%s}`, f.tasks)
+	case "GET_AGENTS":
+		fmt.Fprintf(w, `{"type":"GET_AGENTS","get_agents":%s}`, f.agents)
+	case "SUBSCRIBE":
+		send := func(e string) {
+			fmt.Fprintf(w, "%d\n%s", len(e), e)
+			w.(http.Flusher).Flush()
+		}
+
+		heartbeat := f.heartbeat
+		if heartbeat == 0 {
+			heartbeat = 15
+		}
+
+		send(fmt.Sprintf(`{"type":"SUBSCRIBED","subscribed":{"get_state":{"get_tasks":%s,"get_agents":%s},"heartbeat_interval_seconds":%g}}`, f.tasks, f.agents, heartbeat))
+
+		for {
+			select {
+			case e := <-f.events:
+				send(e)
+			case <-f.done:
+				return
+			}
+		}
+	default:
+		http.Error(w, "unexpected call "+call.Type, http.StatusBadRequest)
+	}
+}
+
+func TestOperatorFetcher(t *testing.T) {
+	f := &fakeOperator{
+		tasks: `{"tasks": [{
+			"name": "web",
+			"task_id": {"value": "web.1"},
+			"framework_id": {"value": "marathon"},
+			"agent_id": {"value": "s1"},
+			"state": "TASK_RUNNING",
+			"resources": [
+				{"name": "cpus", "type": "SCALAR", "scalar": {"value": 0.5}},
+				{"name": "ports", "type": "RANGES", "ranges": {"range": [{"begin": 31000, "end": 31001}]}}
+			],
+			"labels": {"labels": [{"key": "zoidberg_port_0_app_name", "value": "web"}]},
+			"statuses": [{"task_id": {"value": "web.1"}, "state": "TASK_RUNNING", "healthy": true}],
+			"health_check": {"type": "HTTP"}
+		}]}`,
+		agents: `{"agents": [{"agent_info": {"id": {"value": "s1"}, "hostname": "host1"}}]}`,
+		events: make(chan string),
+		done:   make(chan struct{}),
+	}
+
+	s := httptest.NewServer(f)
+	defer s.Close()
+	defer close(f.done)
+
+	fetcher, err := NewOperatorFetcher([]string{s.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	healthy := true
+
+	expected := []Task{
+		{
+			Name:           "web",
+			Host:           "host1",
+			Ports:          []int{31000, 31001},
+			ContainerPorts: []int{31000, 31001},
+			Labels:         map[string]string{"zoidberg_port_0_app_name": "web"},
+			HealthChecked:  true,
+			Healthy:        &healthy,
+		},
+	}
+
+	// tasks are polled until subscription is established
+	tasks, err := fetcher.FetchTasks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(tasks, expected) {
+		t.Errorf("expected: %#v, got: %#v", expected, tasks)
+	}
+
+	wait := func(check func(tasks []Task) bool) {
+		for {
+			tasks, err := fetcher.FetchTasks(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fetcher.mutex.Lock()
+			subscribed := fetcher.subscribed
+			fetcher.mutex.Unlock()
+
+			if subscribed && check(tasks) {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				t.Fatalf("timed out waiting for tasks, got: %#v", tasks)
+			case <-time.After(time.Millisecond * 10):
+			}
+		}
+	}
+
+	wait(func(tasks []Task) bool {
+		return reflect.DeepEqual(tasks, expected)
+	})
+
+	f.events <- `{"type":"AGENT_ADDED","agent_added":{"agent":{"agent_info":{"id":{"value":"s2"},"hostname":"host2"}}}}`
+	f.events <- `{"type":"TASK_ADDED","task_added":{"task":{"name":"api","task_id":{"value":"api.1"},"framework_id":{"value":"marathon"},"agent_id":{"value":"s2"},"state":"TASK_STAGING","resources":[{"name":"ports","ranges":{"range":[{"begin":"32000","end":"32000"}]}}]}}}`
+	f.events <- `{"type":"TASK_UPDATED","task_updated":{"framework_id":{"value":"marathon"},"state":"TASK_RUNNING","status":{"task_id":{"value":"api.1"},"state":"TASK_RUNNING"}}}`
+	f.events <- `{"type":"TASK_UPDATED","task_updated":{"framework_id":{"value":"marathon"},"state":"TASK_RUNNING","status":{"task_id":{"value":"web.1"},"state":"TASK_RUNNING","healthy":false}}}`
+
+	wait(func(tasks []Task) bool {
+		return len(tasks) == 2 && tasks[0].Name == "api" && tasks[0].Host == "host2" &&
+			tasks[1].Healthy != nil && !*tasks[1].Healthy
+	})
+
+	f.events <- `{"type":"TASK_UPDATED","task_updated":{"framework_id":{"value":"marathon"},"state":"TASK_KILLED","status":{"task_id":{"value":"web.1"},"state":"TASK_KILLED"}}}`
+
+	wait(func(tasks []Task) bool {
+		return len(tasks) == 1 && tasks[0].Name == "api" && reflect.DeepEqual(tasks[0].Ports, []int{32000})
+	})
+}
+
+func TestOperatorFetcherHeartbeats(t *testing.T) {
+	f := &fakeOperator{
+		tasks:     `{"tasks": []}`,
+		agents:    `{"agents": []}`,
+		heartbeat: 0.05,
+		events:    make(chan string),
+		done:      make(chan struct{}),
+	}
+
+	s := httptest.NewServer(f)
+	defer s.Close()
+	defer close(f.done)
+
+	fetcher, err := NewOperatorFetcher([]string{s.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscribed := func() bool {
+		fetcher.mutex.Lock()
+		defer fetcher.mutex.Unlock()
+
+		return fetcher.subscribed
+	}
+
+	if _, err = fetcher.FetchTasks(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second * 5)
+	for !subscribed() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for subscription")
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	// the stream is kept open for many heartbeat timeouts
+	for i := 0; i < 50; i++ {
+		f.events <- `{"type":"HEARTBEAT"}`
+		time.Sleep(time.Millisecond * 20)
+
+		if !subscribed() {
+			t.Fatalf("subscription dropped after %d heartbeats", i+1)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package mesos
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -18,12 +19,101 @@ type mesosFramework struct {
 }
 
 type mesosTask struct {
-	ID        string         `json:"id"`
-	Name      string         `json:"name"`
-	State     string         `json:"state"`
-	SlaveID   string         `json:"slave_id"`
-	Resources mesosResources `json:"resources"`
-	Labels    []mesosLabel   `json:"labels"`
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	State       string            `json:"state"`
+	SlaveID     string            `json:"slave_id"`
+	Resources   mesosResources    `json:"resources"`
+	Labels      []mesosLabel      `json:"labels"`
+	Statuses    []mesosTaskStatus `json:"statuses"`
+	Container   mesosContainer    `json:"container"`
+	HealthCheck *json.RawMessage  `json:"health_check"`
+}
+
+type mesosTaskStatus struct {
+	State           string               `json:"state"`
+	Healthy         *bool                `json:"healthy"`
+	ContainerStatus mesosContainerStatus `json:"container_status"`
+}
+
+type mesosContainerStatus struct {
+	NetworkInfos []mesosNetworkInfo `json:"network_infos"`
+}
+
+type mesosContainer struct {
+	Docker       mesosDocker        `json:"docker"`
+	NetworkInfos []mesosNetworkInfo `json:"network_infos"`
+}
+
+type mesosDocker struct {
+	PortMappings []mesosPortMapping `json:"port_mappings"`
+}
+
+type mesosNetworkInfo struct {
+	IPAddresses  []mesosIPAddress   `json:"ip_addresses"`
+	PortMappings []mesosPortMapping `json:"port_mappings"`
+}
+
+type mesosIPAddress struct {
+	Protocol  string `json:"protocol"`
+	IPAddress string `json:"ip_address"`
+}
+
+type mesosPortMapping struct {
+	HostPort      int `json:"host_port"`
+	ContainerPort int `json:"container_port"`
+}
+
+// ipAddresses returns container ip addresses from the most recent status
+func (t mesosTask) ipAddresses() []string {
+	for i := len(t.Statuses) - 1; i >= 0; i-- {
+		ips := []string{}
+		for _, n := range t.Statuses[i].ContainerStatus.NetworkInfos {
+			for _, ip := range n.IPAddresses {
+				if ip.IPAddress != "" {
+					ips = append(ips, ip.IPAddress)
+				}
+			}
+		}
+
+		if len(ips) > 0 {
+			return ips
+		}
+	}
+
+	return nil
+}
+
+// healthy returns health from the most recent status that has it,
+// nil is returned if health check did not report yet or is not defined
+func (t mesosTask) healthy() *bool {
+	for i := len(t.Statuses) - 1; i >= 0; i-- {
+		if t.Statuses[i].Healthy != nil {
+			return t.Statuses[i].Healthy
+		}
+	}
+
+	return nil
+}
+
+// containerPorts returns ports that task listens on inside of the container,
+// falling back to allocated host ports if no port mappings are defined
+func (t mesosTask) containerPorts() []int {
+	mappings := t.Container.Docker.PortMappings
+	for _, n := range t.Container.NetworkInfos {
+		mappings = append(mappings, n.PortMappings...)
+	}
+
+	if len(mappings) == 0 {
+		return t.Resources.Ports
+	}
+
+	ports := make([]int, len(mappings))
+	for i, m := range mappings {
+		ports[i] = m.ContainerPort
+	}
+
+	return ports
 }
 
 type mesosSlave struct {
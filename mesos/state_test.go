@@ -0,0 +1,118 @@
+package mesos
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestTaskContainerNetwork(t *testing.T) {
+	table := []struct {
+		task           string
+		ipAddresses    []string
+		containerPorts []int
+	}{
+		{
+			task:           `{"resources":{"ports":"[31000-31001]"}}`,
+			ipAddresses:    nil,
+			containerPorts: []int{31000, 31001},
+		},
+		{
+			task: `{
+				"resources": {"ports": "[31000-31000]"},
+				"container": {"docker": {"port_mappings": [{"host_port": 31000, "container_port": 80}]}},
+				"statuses": [
+					{"state": "TASK_STARTING"},
+					{"state": "TASK_RUNNING", "container_status": {"network_infos": [{"ip_addresses": [{"ip_address": "10.0.0.5"}]}]}}
+				]
+			}`,
+			ipAddresses:    []string{"10.0.0.5"},
+			containerPorts: []int{80},
+		},
+		{
+			task: `{
+				"container": {"network_infos": [{"port_mappings": [{"container_port": 8080}, {"container_port": 8081}]}]},
+				"statuses": [
+					{"state": "TASK_RUNNING", "container_status": {"network_infos": [{"ip_addresses": [{"ip_address": "10.0.0.6"}, {"ip_address": "10.0.0.7"}]}]}},
+					{"state": "TASK_RUNNING"}
+				]
+			}`,
+			ipAddresses:    []string{"10.0.0.6", "10.0.0.7"},
+			containerPorts: []int{8080, 8081},
+		},
+	}
+
+	for _, row := range table {
+		task := mesosTask{}
+		err := json.Unmarshal([]byte(row.task), &task)
+		if err != nil {
+			t.Fatalf("error decoding task %s: %s", row.task, err)
+		}
+
+		if ips := task.ipAddresses(); !reflect.DeepEqual(ips, row.ipAddresses) {
+			t.Errorf("expected ip addresses: %v, got: %v", row.ipAddresses, ips)
+		}
+
+		if ports := task.containerPorts(); !reflect.DeepEqual(ports, row.containerPorts) {
+			t.Errorf("expected container ports: %v, got: %v", row.containerPorts, ports)
+		}
+	}
+}
+
+func TestTasksFromLeaderHealth(t *testing.T) {
+	state := `{
+		"slaves": [{"id": "s1", "hostname": "host1"}],
+		"frameworks": [{"tasks": [
+			{"name": "unchecked", "state": "TASK_RUNNING", "slave_id": "s1", "resources": {"ports": "[31000-31000]"}},
+			{"name": "pending", "state": "TASK_RUNNING", "slave_id": "s1", "resources": {"ports": "[31001-31001]"},
+				"health_check": {"type": "HTTP"},
+				"statuses": [{"state": "TASK_RUNNING"}]},
+			{"name": "recovered", "state": "TASK_RUNNING", "slave_id": "s1", "resources": {"ports": "[31002-31002]"},
+				"health_check": {"type": "HTTP"},
+				"statuses": [{"state": "TASK_RUNNING", "healthy": false}, {"state": "TASK_RUNNING", "healthy": true}, {"state": "TASK_RUNNING"}]},
+			{"name": "failing", "state": "TASK_RUNNING", "slave_id": "s1", "resources": {"ports": "[31003-31003]"},
+				"health_check": {"type": "HTTP"},
+				"statuses": [{"state": "TASK_RUNNING", "healthy": true}, {"state": "TASK_RUNNING", "healthy": false}]},
+			{"name": "killed", "state": "TASK_KILLED", "slave_id": "s1", "resources": {"ports": "[31004-31004]"}}
+		]}]
+	}`
+
+	s := mesosState{}
+	err := json.Unmarshal([]byte(state), &s)
+	if err != nil {
+		t.Fatalf("error decoding state: %s", err)
+	}
+
+	tasks, err := (&TaskFetcher{}).tasksFromLeader(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	yes, no := true, false
+
+	expected := map[string]struct {
+		checked bool
+		healthy *bool
+	}{
+		"unchecked": {checked: false, healthy: nil},
+		"pending":   {checked: true, healthy: nil},
+		"recovered": {checked: true, healthy: &yes},
+		"failing":   {checked: true, healthy: &no},
+	}
+
+	if len(tasks) != len(expected) {
+		t.Errorf("expected: %d tasks, got: %d", len(expected), len(tasks))
+	}
+
+	for _, task := range tasks {
+		e, ok := expected[task.Name]
+		if !ok {
+			t.Errorf("unexpected task %q", task.Name)
+			continue
+		}
+
+		if task.HealthChecked != e.checked || !reflect.DeepEqual(task.Healthy, e.healthy) {
+			t.Errorf("task %q: expected: %v %v, got: %v %v", task.Name, e.checked, e.healthy, task.HealthChecked, task.Healthy)
+		}
+	}
+}
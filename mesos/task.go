@@ -2,8 +2,15 @@ package mesos
 
 // Task represents a single running Mesos task
 type Task struct {
-	Name   string
-	Host   string
-	Ports  []int
-	Labels map[string]string
+	Name           string
+	Host           string
+	Ports          []int
+	IPAddresses    []string
+	ContainerPorts []int
+	Labels         map[string]string
+	// HealthChecked is true if task has a health check defined
+	HealthChecked bool
+	// Healthy is the result of the last health check,
+	// nil if there is no health check or it did not report yet
+	Healthy *bool
 }
@@ -0,0 +1,170 @@
+package zoidberg
+
+import (
+	"time"
+
+	"github.com/bobrik/zoidberg/metrics"
+)
+
+// explorerMetrics holds metrics exposed by explorer
+type explorerMetrics struct {
+	registry          *metrics.Registry
+	discoveryDuration *metrics.Vec
+	discoveryErrors   *metrics.Vec
+	discoveryAge      *metrics.Vec
+	apps              *metrics.Vec
+	servers           *metrics.Vec
+	weights           *metrics.Vec
+	updateDuration    *metrics.Vec
+	updates           *metrics.Vec
+	updateSuccess     *metrics.Vec
+	updateSkips       *metrics.Vec
+}
+
+// newExplorerMetrics creates metrics for the explorer
+func newExplorerMetrics(e *Explorer) *explorerMetrics {
+	m := &explorerMetrics{
+		registry: metrics.NewRegistry(),
+		discoveryDuration: metrics.NewVec(
+			metrics.Summary,
+			"zoidberg_discovery_duration_seconds",
+			"Time spent in finders during discovery.",
+			"finder", "source",
+		),
+		discoveryErrors: metrics.NewVec(
+			metrics.Counter,
+			"zoidberg_discovery_errors_total",
+			"Number of failed discoveries.",
+			"finder", "source",
+		),
+		discoveryAge: metrics.NewVec(
+			metrics.Gauge,
+			"zoidberg_last_discovery_age_seconds",
+			"Time since the last successful discovery.",
+		),
+		apps: metrics.NewVec(
+			metrics.Gauge,
+			"zoidberg_apps",
+			"Number of discovered apps.",
+			"group",
+		),
+		servers: metrics.NewVec(
+			metrics.Gauge,
+			"zoidberg_servers",
+			"Number of discovered servers.",
+			"group", "app", "version",
+		),
+		weights: metrics.NewVec(
+			metrics.Gauge,
+			"zoidberg_version_weight",
+			"Configured weight of app version.",
+			"group", "app", "version",
+		),
+		updateDuration: metrics.NewVec(
+			metrics.Summary,
+			"zoidberg_balancer_update_duration_seconds",
+			"Time spent pushing state to balancers.",
+			"group", "balancer",
+		),
+		updates: metrics.NewVec(
+			metrics.Counter,
+			"zoidberg_balancer_updates_total",
+			"Number of state pushes to balancers by result.",
+			"group", "balancer", "result",
+		),
+		updateSuccess: metrics.NewVec(
+			metrics.Gauge,
+			"zoidberg_balancer_last_success_timestamp_seconds",
+			"Time of the last state push accepted by balancer.",
+			"group", "balancer",
+		),
+		updateSkips: metrics.NewVec(
+			metrics.Counter,
+			"zoidberg_balancer_update_skips_total",
+			"Number of skipped state pushes to balancers due to laziness.",
+			"group", "balancer",
+		),
+	}
+
+	m.registry.Register(
+		m.discoveryDuration,
+		m.discoveryErrors,
+		m.discoveryAge,
+		m.apps,
+		m.servers,
+		m.weights,
+		m.updateDuration,
+		m.updates,
+		m.updateSuccess,
+		m.updateSkips,
+	)
+
+	m.registry.RegisterCollector(func() {
+		m.collect(e)
+	})
+
+	return m
+}
+
+// collect updates metrics that reflect the current state of explorer
+func (m *explorerMetrics) collect(e *Explorer) {
+	m.discoveryAge.Reset()
+	m.apps.Reset()
+	m.servers.Reset()
+	m.weights.Reset()
+
+	e.mutex.Lock()
+	discoveries, discovered := e.discoveries, e.discovered
+	e.mutex.Unlock()
+
+	if !discovered.IsZero() {
+		m.discoveryAge.Set(time.Since(discovered).Seconds())
+	}
+
+	for n, d := range discoveries {
+		m.apps.Set(float64(len(d.Apps)), n)
+
+		for _, a := range d.Apps {
+			for _, s := range a.Servers {
+				m.servers.Add(1, n, a.Name, s.Version)
+			}
+		}
+	}
+
+	for n, g := range e.groupList() {
+		for a, versions := range e.getState(g).Versions {
+			for v, version := range versions {
+				m.weights.Set(float64(version.Weight), n, a, v)
+			}
+		}
+	}
+}
+
+// observeDiscovery records duration and result of a finder call
+func (m *explorerMetrics) observeDiscovery(finder, source string, started time.Time, err error) {
+	m.discoveryDuration.Observe(time.Since(started).Seconds(), finder, source)
+	if err != nil {
+		m.discoveryErrors.Add(1, finder, source)
+	}
+}
+
+// observeUpdate records duration and result of a balancer update
+func (m *explorerMetrics) observeUpdate(group, balancer string, started time.Time, err error) {
+	m.updateDuration.Observe(time.Since(started).Seconds(), group, balancer)
+
+	if err != nil {
+		m.updates.Add(1, group, balancer, "failure")
+		return
+	}
+
+	m.updates.Add(1, group, balancer, "success")
+	m.updateSuccess.Set(float64(time.Now().UnixNano())/float64(time.Second), group, balancer)
+}
+
+// forgetBalancer removes metrics of a balancer that is gone from the group
+func (m *explorerMetrics) forgetBalancer(group, balancer string) {
+	m.updateDuration.Delete(group, balancer)
+	m.updates.Delete(group, balancer)
+	m.updateSuccess.Delete(group, balancer)
+	m.updateSkips.Delete(group, balancer)
+}
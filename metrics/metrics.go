@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Kind is a type of metric in prometheus text format
+type Kind string
+
+const (
+	// Counter only goes up
+	Counter Kind = "counter"
+	// Gauge goes up and down
+	Gauge Kind = "gauge"
+	// Summary tracks sum and count of observations
+	Summary Kind = "summary"
+)
+
+// Vec is a metric partitioned by label values
+type Vec struct {
+	name   string
+	help   string
+	kind   Kind
+	labels []string
+	values map[string]*value
+	mutex  sync.Mutex
+}
+
+// value is a single value of a metric with specific label values
+type value struct {
+	labels []string
+	value  float64
+	count  uint64
+}
+
+// NewVec creates a new metric with the name, description and label names
+func NewVec(kind Kind, name, help string, labels ...string) *Vec {
+	return &Vec{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		labels: labels,
+		values: map[string]*value{},
+	}
+}
+
+// get returns value for label values, must be called with mutex held
+func (v *Vec) get(labels []string) *value {
+	if len(labels) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", v.name, len(v.labels), len(labels)))
+	}
+
+	k := strings.Join(labels, "\xff")
+	if _, ok := v.values[k]; !ok {
+		v.values[k] = &value{labels: append([]string{}, labels...)}
+	}
+
+	return v.values[k]
+}
+
+// Add adds delta to the value of a counter or a gauge
+func (v *Vec) Add(delta float64, labels ...string) {
+	v.mutex.Lock()
+	v.get(labels).value += delta
+	v.mutex.Unlock()
+}
+
+// Set sets the value of a gauge
+func (v *Vec) Set(x float64, labels ...string) {
+	v.mutex.Lock()
+	v.get(labels).value = x
+	v.mutex.Unlock()
+}
+
+// Observe adds an observation to a summary
+func (v *Vec) Observe(x float64, labels ...string) {
+	v.mutex.Lock()
+	s := v.get(labels)
+	s.value += x
+	s.count++
+	v.mutex.Unlock()
+}
+
+// Delete removes values with the leading label values, so values
+// of all results of a balancer are removed with group and balancer
+func (v *Vec) Delete(labels ...string) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	for k, x := range v.values {
+		if len(x.labels) >= len(labels) && reflect.DeepEqual(x.labels[:len(labels)], labels) {
+			delete(v.values, k)
+		}
+	}
+}
+
+// Reset removes all values
+func (v *Vec) Reset() {
+	v.mutex.Lock()
+	v.values = map[string]*value{}
+	v.mutex.Unlock()
+}
+
+// Write writes the metric in prometheus text format
+func (v *Vec) Write(w io.Writer) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintf(b, "# HELP %s %s\n", v.name, strings.Replace(v.help, "\n", " ", -1))
+	fmt.Fprintf(b, "# TYPE %s %s\n", v.name, v.kind)
+
+	keys := make([]string, 0, len(v.values))
+	for k := range v.values {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		x := v.values[k]
+		l := v.formatLabels(x.labels)
+
+		if v.kind == Summary {
+			fmt.Fprintf(b, "%s_sum%s %s\n", v.name, l, formatFloat(x.value))
+			fmt.Fprintf(b, "%s_count%s %d\n", v.name, l, x.count)
+			continue
+		}
+
+		fmt.Fprintf(b, "%s%s %s\n", v.name, l, formatFloat(x.value))
+	}
+
+	return b.Flush()
+}
+
+// formatLabels returns label set in prometheus text format
+func (v *Vec) formatLabels(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	r := make([]string, len(values))
+	for i, l := range values {
+		r[i] = fmt.Sprintf("%s=\"%s\"", v.labels[i], escape(l))
+	}
+
+	return "{" + strings.Join(r, ",") + "}"
+}
+
+// escape escapes label value for prometheus text format
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+// formatFloat formats value for prometheus text format
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestVecWrite(t *testing.T) {
+	table := []struct {
+		vec      *Vec
+		update   func(v *Vec)
+		expected string
+	}{
+		{
+			vec:    NewVec(Gauge, "test_age_seconds", "Age."),
+			update: func(v *Vec) {},
+			expected: "# HELP test_age_seconds Age.\n" +
+				"# TYPE test_age_seconds gauge\n",
+		},
+		{
+			vec: NewVec(Counter, "test_errors_total", "Errors.", "finder"),
+			update: func(v *Vec) {
+				v.Add(1, "mesos")
+				v.Add(2, "mesos")
+				v.Add(1, "marathon")
+			},
+			expected: "# HELP test_errors_total Errors.\n" +
+				"# TYPE test_errors_total counter\n" +
+				"test_errors_total{finder=\"marathon\"} 1\n" +
+				"test_errors_total{finder=\"mesos\"} 3\n",
+		},
+		{
+			vec: NewVec(Summary, "test_duration_seconds", "Duration.", "group", "balancer"),
+			update: func(v *Vec) {
+				v.Observe(0.5, "a", "[::1]:80")
+				v.Observe(0.25, "a", "[::1]:80")
+			},
+			expected: "# HELP test_duration_seconds Duration.\n" +
+				"# TYPE test_duration_seconds summary\n" +
+				"test_duration_seconds_sum{group=\"a\",balancer=\"[::1]:80\"} 0.75\n" +
+				"test_duration_seconds_count{group=\"a\",balancer=\"[::1]:80\"} 2\n",
+		},
+		{
+			vec: NewVec(Counter, "test_updates_total", "Updates.", "group", "balancer", "result"),
+			update: func(v *Vec) {
+				v.Add(1, "a", "[::1]:80", "success")
+				v.Add(1, "a", "[::1]:80", "failure")
+				v.Add(1, "a", "[::1]:81", "success")
+				v.Add(1, "b", "[::1]:80", "success")
+				v.Delete("a", "[::1]:80")
+			},
+			expected: "# HELP test_updates_total Updates.\n" +
+				"# TYPE test_updates_total counter\n" +
+				"test_updates_total{group=\"a\",balancer=\"[::1]:81\",result=\"success\"} 1\n" +
+				"test_updates_total{group=\"b\",balancer=\"[::1]:80\",result=\"success\"} 1\n",
+		},
+		{
+			vec: NewVec(Gauge, "test_weight", "Weight.", "app"),
+			update: func(v *Vec) {
+				v.Set(5, "quote\"and\\slash")
+			},
+			expected: "# HELP test_weight Weight.\n" +
+				"# TYPE test_weight gauge\n" +
+				"test_weight{app=\"quote\\\"and\\\\slash\"} 5\n",
+		},
+	}
+
+	for _, row := range table {
+		row.update(row.vec)
+
+		b := bytes.NewBuffer(nil)
+		err := row.vec.Write(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if b.String() != row.expected {
+			t.Errorf("expected:\n%s\ngot:\n%s", row.expected, b.String())
+		}
+	}
+}
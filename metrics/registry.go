@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/bobrik/zoidberg/logging"
+)
+
+var logger = logging.Component("metrics")
+
+// Registry is a set of metrics exposed together
+type Registry struct {
+	vecs       []*Vec
+	collectors []func()
+	mutex      sync.Mutex
+}
+
+// NewRegistry creates a new empty Registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds metrics to the registry
+func (r *Registry) Register(vecs ...*Vec) {
+	r.mutex.Lock()
+	r.vecs = append(r.vecs, vecs...)
+	r.mutex.Unlock()
+}
+
+// RegisterCollector adds a function that updates metrics
+// right before they are exposed to reflect the current state
+func (r *Registry) RegisterCollector(collector func()) {
+	r.mutex.Lock()
+	r.collectors = append(r.collectors, collector)
+	r.mutex.Unlock()
+}
+
+// ServeHTTP exposes metrics in prometheus text format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		http.Error(w, "expected GET", http.StatusBadRequest)
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, c := range r.collectors {
+		c()
+	}
+
+	w.Header().Add("Content-type", "text/plain; version=0.0.4")
+
+	for _, v := range r.vecs {
+		err := v.Write(w)
+		if err != nil {
+			logger.Warnf("error sending metrics: %s", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package zoidberg
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/bobrik/zoidberg/state"
+)
+
+// ReaperConfig configures garbage collection of versions
+// of apps and versions that are no longer discovered
+type ReaperConfig struct {
+	// OrphanTTL is time since app or version was discovered to consider
+	// its versions orphaned and report them, 0 disables the reaper
+	OrphanTTL time.Duration
+	// PruneTTL is time since app or version was discovered
+	// to remove its versions from state, 0 disables pruning
+	PruneTTL time.Duration
+}
+
+// Orphan is an app or a version of an app that has versions set,
+// but hasn't been discovered for a while, version is empty if
+// the whole app is orphaned
+type Orphan struct {
+	App      string     `json:"app"`
+	Version  string     `json:"version,omitempty"`
+	LastSeen time.Time  `json:"last_seen"`
+	PruneAt  *time.Time `json:"prune_at,omitempty"`
+}
+
+// SetReaperConfig sets configuration of the reaper
+func (e *Explorer) SetReaperConfig(c ReaperConfig) {
+	e.mutex.Lock()
+	e.reaper = c
+	e.mutex.Unlock()
+}
+
+// markSeen remembers when apps and their versions were discovered, apps
+// and versions with state that were never discovered are considered seen
+// when they are first noticed, so versions set ahead of deploys are kept
+func (e *Explorer) markSeen(discoveries map[string]*Discovery, now time.Time) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for n, d := range discoveries {
+		g, ok := e.groups[n]
+		if !ok {
+			continue
+		}
+
+		for name, app := range d.Apps {
+			g.seen[name] = now
+
+			if g.seenVersions[name] == nil {
+				g.seenVersions[name] = map[string]time.Time{}
+			}
+
+			for _, s := range app.Servers {
+				g.seenVersions[name][s.Version] = now
+			}
+		}
+	}
+
+	for _, g := range e.groups {
+		for app, versions := range g.state.Versions {
+			if _, ok := g.seen[app]; !ok {
+				g.seen[app] = now
+			}
+
+			if g.seenVersions[app] == nil {
+				g.seenVersions[app] = map[string]time.Time{}
+			}
+
+			for version := range versions {
+				if _, ok := g.seenVersions[app][version]; !ok {
+					g.seenVersions[app][version] = now
+				}
+			}
+		}
+	}
+}
+
+// orphans returns orphaned versions of the group, apps and
+// versions that were not noticed by markSeen yet were set
+// after the last discovery and are considered seen now
+func (e *Explorer) orphans(g *group, now time.Time) []Orphan {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	c := e.reaper
+	if c.OrphanTTL == 0 {
+		return []Orphan{}
+	}
+
+	orphans := []Orphan{}
+
+	add := func(app, version string, seen time.Time) {
+		o := Orphan{App: app, Version: version, LastSeen: seen}
+		if c.PruneTTL > 0 {
+			at := seen.Add(c.PruneTTL)
+			o.PruneAt = &at
+		}
+
+		orphans = append(orphans, o)
+	}
+
+	for app, versions := range g.state.Versions {
+		seen, ok := g.seen[app]
+		if !ok {
+			seen = now
+		}
+
+		if now.Sub(seen) > c.OrphanTTL {
+			add(app, "", seen)
+			continue
+		}
+
+		for version := range versions {
+			seen, ok := g.seenVersions[app][version]
+			if !ok {
+				seen = now
+			}
+
+			if now.Sub(seen) > c.OrphanTTL {
+				add(app, version, seen)
+			}
+		}
+	}
+
+	sort.Slice(orphans, func(i, j int) bool {
+		if orphans[i].App != orphans[j].App {
+			return orphans[i].App < orphans[j].App
+		}
+
+		return orphans[i].Version < orphans[j].Version
+	})
+
+	return orphans
+}
+
+// reap removes orphaned versions that are due for pruning
+// from state of all groups and persists changed state
+func (e *Explorer) reap(now time.Time) {
+	for _, g := range e.groupList() {
+		pruned := false
+
+		for _, o := range e.orphans(g, now) {
+			if o.PruneAt == nil || now.Before(*o.PruneAt) {
+				continue
+			}
+
+			pruned = true
+
+			if o.Version == "" {
+				e.deleteVersions(g, o.App)
+				e.events.publish(Event{Type: EventVersionsDeleted, Group: g.name, App: o.App})
+
+				logger.With("group", g.name, "app", o.App, "last_seen", o.LastSeen).Infof("pruned versions of orphaned app")
+				continue
+			}
+
+			versions := e.patchVersions(g, o.App, map[string]*state.Version{o.Version: nil})
+			e.events.publish(Event{Type: EventVersionsChanged, Group: g.name, App: o.App, Versions: versions})
+
+			logger.With("group", g.name, "app", o.App, "version", o.Version, "last_seen", o.LastSeen).Infof("pruned orphaned version")
+		}
+
+		if !pruned {
+			continue
+		}
+
+		err := e.persistState(g)
+		if err != nil {
+			logger.With("group", g.name).Errorf("error persisting state after pruning: %s", err)
+		}
+	}
+}
+
+// forget drops discovery times of apps and versions that have no state
+// and were not discovered for prune ttl, or orphan ttl if pruning is
+// disabled, so that discovery times do not pile up for removed apps
+func (e *Explorer) forget(now time.Time) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	ttl := e.reaper.PruneTTL
+	if ttl == 0 {
+		ttl = e.reaper.OrphanTTL
+	}
+
+	for _, g := range e.groups {
+		for app, versions := range g.seenVersions {
+			for version, seen := range versions {
+				if _, ok := g.state.Versions[app][version]; !ok && now.Sub(seen) > ttl {
+					delete(versions, version)
+				}
+			}
+
+			if len(versions) == 0 {
+				delete(g.seenVersions, app)
+			}
+		}
+
+		for app, seen := range g.seen {
+			if _, ok := g.state.Versions[app]; !ok && now.Sub(seen) > ttl {
+				delete(g.seen, app)
+			}
+		}
+	}
+}
+
+// serveOrphans serves orphaned versions of the group
+func (e *Explorer) serveOrphans(w http.ResponseWriter, req *http.Request, g *group) {
+	if req.Method != "GET" {
+		http.Error(w, "expected GET", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Add("Content-type", "application/json")
+	err := json.NewEncoder(w).Encode(e.orphans(g, time.Now()))
+	if err != nil {
+		logger.With("group", g.name).Warnf("error sending orphans: %s", err)
+	}
+}
@@ -0,0 +1,177 @@
+package zoidberg
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bobrik/zoidberg/application"
+	"github.com/bobrik/zoidberg/state"
+)
+
+func TestOrphans(t *testing.T) {
+	created := time.Now().Add(-time.Hour * 24)
+	now := created.Add(time.Hour * 10)
+
+	g := &group{
+		name: "g",
+		state: state.State{
+			Versions: map[string]state.Versions{
+				"live":  {"1": {Weight: 1}, "2": {Weight: 1}},
+				"gone":  {"1": {Weight: 1}},
+				"never": {"1": {Weight: 1}},
+			},
+		},
+		seen:         map[string]time.Time{},
+		seenVersions: map[string]map[string]time.Time{},
+	}
+
+	e := &Explorer{groups: map[string]*group{"g": g}}
+
+	e.markSeen(map[string]*Discovery{
+		"g": {
+			Apps: application.Apps{
+				"live": {Name: "live", Servers: []application.Server{{Host: "a", Port: 1, Version: "2"}}},
+				"gone": {Name: "gone", Servers: []application.Server{{Host: "b", Port: 1, Version: "1"}}},
+			},
+		},
+	}, created.Add(time.Hour*2))
+
+	e.markSeen(map[string]*Discovery{
+		"g": {
+			Apps: application.Apps{
+				"live": {Name: "live", Servers: []application.Server{{Host: "a", Port: 1, Version: "2"}}},
+			},
+		},
+	}, now)
+
+	if o := e.orphans(g, now); len(o) != 0 {
+		t.Errorf("expected no orphans with disabled reaper, got: %v", o)
+	}
+
+	e.SetReaperConfig(ReaperConfig{OrphanTTL: time.Hour, PruneTTL: time.Hour * 12})
+
+	expected := []Orphan{
+		{App: "gone", LastSeen: created.Add(time.Hour * 2), PruneAt: timePtr(created.Add(time.Hour * 14))},
+		{App: "live", Version: "1", LastSeen: created.Add(time.Hour * 2), PruneAt: timePtr(created.Add(time.Hour * 14))},
+		{App: "never", LastSeen: created.Add(time.Hour * 2), PruneAt: timePtr(created.Add(time.Hour * 14))},
+	}
+
+	o := e.orphans(g, now)
+	if !reflect.DeepEqual(o, expected) {
+		t.Errorf("expected: %+v, got: %+v", expected, o)
+	}
+}
+
+func TestReapVersionsSetBeforeDiscovery(t *testing.T) {
+	started := time.Now().Add(-time.Hour * 24)
+	now := time.Now()
+
+	g := &group{
+		name: "g",
+		state: state.State{
+			Versions: map[string]state.Versions{
+				"live": {"1": {Weight: 1}},
+			},
+		},
+		seen:         map[string]time.Time{},
+		seenVersions: map[string]map[string]time.Time{},
+	}
+
+	e := &Explorer{groups: map[string]*group{"g": g}}
+	e.SetReaperConfig(ReaperConfig{OrphanTTL: time.Hour, PruneTTL: time.Hour * 12})
+
+	discoveries := map[string]*Discovery{
+		"g": {
+			Apps: application.Apps{
+				"live": {Name: "live", Servers: []application.Server{{Host: "a", Port: 1, Version: "1"}}},
+			},
+		},
+	}
+
+	// the group has been running for longer than prune ttl
+	e.markSeen(discoveries, started)
+	e.markSeen(discoveries, now.Add(-time.Minute))
+
+	// canary version and versions of a new app are set before deploys
+	g.state.Versions["live"]["2"] = state.Version{Weight: 1}
+	g.state.Versions["new"] = state.Versions{"1": {Weight: 1}}
+
+	if o := e.orphans(g, now); len(o) != 0 {
+		t.Errorf("expected no orphans before the next cycle, got: %+v", o)
+	}
+
+	e.markSeen(discoveries, now)
+	e.reap(now)
+	e.forget(now)
+
+	expected := map[string]state.Versions{
+		"live": {"1": {Weight: 1}, "2": {Weight: 1}},
+		"new":  {"1": {Weight: 1}},
+	}
+
+	if !reflect.DeepEqual(g.state.Versions, expected) {
+		t.Errorf("expected versions to survive a cycle: %v, got: %v", expected, g.state.Versions)
+	}
+
+	expectedOrphans := []Orphan{
+		{App: "live", Version: "2", LastSeen: now, PruneAt: timePtr(now.Add(time.Hour * 12))},
+		{App: "new", LastSeen: now, PruneAt: timePtr(now.Add(time.Hour * 12))},
+	}
+
+	e.markSeen(discoveries, now.Add(time.Hour*2))
+
+	if o := e.orphans(g, now.Add(time.Hour*2)); !reflect.DeepEqual(o, expectedOrphans) {
+		t.Errorf("expected orphans: %+v, got: %+v", expectedOrphans, o)
+	}
+}
+
+func TestForget(t *testing.T) {
+	now := time.Now()
+
+	g := &group{
+		name: "g",
+		state: state.State{
+			Versions: map[string]state.Versions{
+				"kept": {"1": {Weight: 1}},
+			},
+		},
+		seen: map[string]time.Time{
+			"kept":   now.Add(-time.Hour * 20),
+			"recent": now.Add(-time.Minute),
+			"gone":   now.Add(-time.Hour * 20),
+		},
+		seenVersions: map[string]map[string]time.Time{
+			"kept":   {"1": now.Add(-time.Hour * 20), "2": now.Add(-time.Hour * 20), "3": now},
+			"recent": {"1": now.Add(-time.Minute)},
+			"gone":   {"1": now.Add(-time.Hour * 20)},
+		},
+	}
+
+	e := &Explorer{groups: map[string]*group{"g": g}}
+	e.SetReaperConfig(ReaperConfig{OrphanTTL: time.Hour, PruneTTL: time.Hour * 12})
+
+	e.forget(now)
+
+	seen := map[string]time.Time{
+		"kept":   now.Add(-time.Hour * 20),
+		"recent": now.Add(-time.Minute),
+	}
+
+	if !reflect.DeepEqual(g.seen, seen) {
+		t.Errorf("expected seen: %v, got: %v", seen, g.seen)
+	}
+
+	seenVersions := map[string]map[string]time.Time{
+		"kept":   {"1": now.Add(-time.Hour * 20), "3": now},
+		"recent": {"1": now.Add(-time.Minute)},
+	}
+
+	if !reflect.DeepEqual(g.seenVersions, seenVersions) {
+		t.Errorf("expected seen versions: %v, got: %v", seenVersions, g.seenVersions)
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
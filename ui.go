@@ -0,0 +1,24 @@
+package zoidberg
+
+import (
+	_ "embed" // web ui is embedded into the binary
+	"net/http"
+)
+
+//go:embed ui/index.html
+var uiPage []byte
+
+// serveUI serves web ui that shows apps, versions and balancers
+// of balancer groups and changes weights through the api
+func serveUI(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		http.Error(w, "expected GET", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Add("Content-type", "text/html; charset=utf-8")
+	_, err := w.Write(uiPage)
+	if err != nil {
+		logger.Warnf("error sending ui: %s", err)
+	}
+}
@@ -0,0 +1,129 @@
+// Package watch keeps parsed contents of files that change over time
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bobrik/zoidberg/logging"
+)
+
+var logger = logging.Component("watch")
+
+// File is a file that is parsed again when it changes, changes are
+// reported by inotify where it is available, modification time and
+// size of the file are checked on every read as well to catch changes
+// that inotify misses, like replaced symlinks of parent directories
+type File struct {
+	path     string
+	parse    func(b []byte) (interface{}, error)
+	watching bool
+	watcher  io.Closer
+	closed   bool
+	changed  bool
+	modified time.Time
+	size     int64
+	value    interface{}
+	mutex    sync.Mutex
+}
+
+// NewFile creates a new file that is parsed with the parse function
+func NewFile(path string, parse func(b []byte) (interface{}, error)) (*File, error) {
+	if path == "" {
+		return nil, errors.New("empty file path")
+	}
+
+	if parse == nil {
+		return nil, errors.New("no parse function for file")
+	}
+
+	return &File{
+		path:  path,
+		parse: parse,
+	}, nil
+}
+
+// Path returns the path of the file
+func (f *File) Path() string {
+	return f.path
+}
+
+// Value returns parsed contents of the file, reading it again if it
+// changed since the last read, parse errors are prefixed with the path
+func (f *File) Value() (interface{}, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if !f.watching && !f.closed {
+		w, err := notify(f.path, f.change)
+		if err != nil {
+			logger.With("file", f.path).Warnf("error watching file, relying on modification time: %s", err)
+		}
+
+		f.watching = true
+		f.watcher = w
+	}
+
+	s, err := os.Stat(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.value != nil && !f.changed && s.ModTime().Equal(f.modified) && s.Size() == f.size {
+		return f.value, nil
+	}
+
+	// changes that happen while the file is read are picked up next time
+	f.changed = false
+
+	b, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := f.parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%s", f.path, err)
+	}
+
+	if f.value != nil {
+		logger.With("file", f.path).Infof("file reloaded")
+	}
+
+	f.value = v
+	f.modified = s.ModTime()
+	f.size = s.Size()
+
+	return v, nil
+}
+
+// Close stops watching the file, it can still be read afterwards,
+// but only modification time and size are checked for changes
+func (f *File) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.closed = true
+
+	if f.watcher == nil {
+		return nil
+	}
+
+	err := f.watcher.Close()
+	f.watcher = nil
+
+	return err
+}
+
+// change marks the file as changed, so it is read again on the next read
+func (f *File) change() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.changed = true
+}
@@ -0,0 +1,155 @@
+package watch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zoidberg-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "file")
+
+	write := func(content string, modified time.Time) {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	parses := 0
+
+	f, err := NewFile(path, func(b []byte) (interface{}, error) {
+		parses++
+
+		if strings.HasPrefix(string(b), "bad") {
+			return nil, os.ErrInvalid
+		}
+
+		return string(b), nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(expected string, expectedParses int) {
+		t.Helper()
+
+		v, err := f.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v != expected {
+			t.Errorf("expected %q, got %q", expected, v)
+		}
+
+		if parses != expectedParses {
+			t.Errorf("expected %d parses, got %d", expectedParses, parses)
+		}
+	}
+
+	now := time.Now()
+
+	write("one", now)
+	check("one", 1)
+	check("one", 1)
+
+	write("two", now.Add(time.Second))
+	check("two", 2)
+
+	// same size and modification time are only noticed with inotify
+	write("six", now.Add(time.Second))
+
+	if runtime.GOOS != "linux" {
+		t.Skip("inotify is only available on linux")
+	}
+
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		v, err := f.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v == "six" {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("edited file with the same size and modification time is not picked up")
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	write("bad", now.Add(time.Second*2))
+
+	_, err = f.Value()
+	if err == nil || err.Error() != path+":"+os.ErrInvalid.Error() {
+		t.Errorf("expected parse error prefixed with path, got: %v", err)
+	}
+}
+
+func TestFileClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zoidberg-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "file")
+	if err = ioutil.WriteFile(path, []byte("one"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	parse := func(b []byte) (interface{}, error) {
+		return string(b), nil
+	}
+
+	before := runtime.NumGoroutine()
+
+	// files are replaced on every reload of configuration
+	for i := 0; i < 100; i++ {
+		f, err := NewFile(path, parse)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = f.Value(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = f.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if v, err := f.Value(); err != nil || v != "one" {
+			t.Fatalf("expected closed file to be readable, got: %v, %v", v, err)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second * 5)
+	for runtime.NumGoroutine() > before+10 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected watchers to stop, %d goroutines before, %d after", before, runtime.NumGoroutine())
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+}
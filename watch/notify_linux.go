@@ -0,0 +1,63 @@
+package watch
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// notify calls f every time the file may have changed until the returned
+// watcher is closed, the directory of the file is watched, so replaced
+// and recreated files are noticed
+func notify(path string, f func()) (io.Closer, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	mask := uint32(syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO)
+
+	_, err = syscall.InotifyAddWatch(fd, filepath.Dir(path), mask)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	// non-blocking descriptor is handled by the runtime poller,
+	// so closing the file interrupts reads and stops the watcher
+	w := os.NewFile(uintptr(fd), "inotify:"+path)
+
+	name := []byte(filepath.Base(path))
+
+	go func() {
+		buf := make([]byte, (syscall.SizeofInotifyEvent+syscall.NAME_MAX+1)*16)
+
+		for {
+			n, err := w.Read(buf)
+			if err != nil {
+				if !errors.Is(err, os.ErrClosed) {
+					logger.With("file", path).Warnf("error reading inotify events, relying on modification time: %s", err)
+				}
+
+				return
+			}
+
+			for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+				e := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+
+				start := offset + syscall.SizeofInotifyEvent
+				offset = start + int(e.Len)
+
+				if e.Mask&syscall.IN_Q_OVERFLOW != 0 || bytes.Equal(bytes.TrimRight(buf[start:offset], "\x00"), name) {
+					f()
+				}
+			}
+		}
+	}()
+
+	return w, nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package watch
+
+import (
+	"errors"
+	"io"
+)
+
+// notify is not supported without inotify, changes of the file
+// are only noticed by its modification time and size
+func notify(path string, f func()) (io.Closer, error) {
+	return nil, errors.New("inotify is only available on linux")
+}